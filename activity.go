@@ -0,0 +1,68 @@
+package todolist_app
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ActivityEntityList   = "list"
+	ActivityEntityItem   = "item"
+	ActivityEntityMember = "member"
+)
+
+const (
+	ActionCreated       = "created"
+	ActionUpdated       = "updated"
+	ActionDeleted       = "deleted"
+	ActionRestored      = "restored"
+	ActionMoved         = "moved"
+	ActionReordered     = "reordered"
+	ActionRepositioned  = "repositioned"
+	ActionSeriesUpdated = "series_updated"
+	ActionSeriesStopped = "series_stopped"
+	ActionMemberSaved   = "member_saved"
+	ActionMemberRemoved = "member_removed"
+)
+
+// Activity is an entry of the append-only log of changes to a list and its items.
+type Activity struct {
+	Id            int       `json:"id" db:"id"`
+	ListId        int       `json:"list_id" db:"list_id"`
+	ItemId        *int      `json:"item_id" db:"item_id"`
+	ActorId       *int      `json:"actor_id" db:"actor_id"`
+	ActorUsername *string   `json:"actor_username" db:"actor_username"`
+	Entity        string    `json:"entity" db:"entity"`
+	Action        string    `json:"action" db:"action"`
+	Changes       Changes   `json:"changes" db:"changes"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Changes maps changed fields to their values before and after a change. It is stored as jsonb.
+type Changes map[string]Change
+
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(c)
+}
+
+func (c *Changes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into Changes", src)
+	}
+}
@@ -0,0 +1,92 @@
+package todolist_app
+
+import "fmt"
+
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+const (
+	// BatchModeAtomic applies all operations or none of them.
+	BatchModeAtomic = "atomic"
+	// BatchModePartial applies the operations that succeed and reports the others.
+	BatchModePartial = "partial"
+)
+
+const MaxBatchOperations = 100
+
+// BatchOperation is one step of a batch on the items of a list. Create takes Item,
+// update takes Id and Input, delete takes Id.
+type BatchOperation struct {
+	Op    string           `json:"op" binding:"required"`
+	Id    *int             `json:"id"`
+	Item  *TodoItem        `json:"item"`
+	Input *UpdateItemInput `json:"input"`
+}
+
+func (o BatchOperation) Validate() error {
+	switch o.Op {
+	case BatchOpCreate:
+		if o.Item == nil {
+			return NewValidationError("create requires item")
+		}
+	case BatchOpUpdate:
+		if o.Id == nil || o.Input == nil {
+			return NewValidationError("update requires id and input")
+		}
+
+		return o.Input.Validate()
+	case BatchOpDelete:
+		if o.Id == nil {
+			return NewValidationError("delete requires id")
+		}
+	default:
+		return NewValidationError(fmt.Sprintf("unknown operation %q", o.Op))
+	}
+
+	return nil
+}
+
+type BatchInput struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations" binding:"required,dive"`
+}
+
+func (i BatchInput) Validate() error {
+	if i.Mode != "" && i.Mode != BatchModeAtomic && i.Mode != BatchModePartial {
+		return NewValidationError(fmt.Sprintf("unknown mode %q", i.Mode))
+	}
+
+	if len(i.Operations) == 0 || len(i.Operations) > MaxBatchOperations {
+		return NewValidationError(fmt.Sprintf("a batch has between 1 and %d operations", MaxBatchOperations))
+	}
+
+	for n, op := range i.Operations {
+		if err := op.Validate(); err != nil {
+			return NewValidationError(fmt.Sprintf("operation %d: %s", n, err))
+		}
+	}
+
+	return nil
+}
+
+// Atomic reports whether a failed operation rolls back the whole batch, which is the default.
+func (i BatchInput) Atomic() bool {
+	return i.Mode != BatchModePartial
+}
+
+// BatchResult is the outcome of the operation at Index. Id is the affected item,
+// Error is set when the operation failed.
+type BatchResult struct {
+	Index int         `json:"index"`
+	Op    string      `json:"op"`
+	Id    int         `json:"id,omitempty"`
+	Error *BatchError `json:"error,omitempty"`
+}
+
+type BatchError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/milmenderov/todolist-app"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -31,24 +34,38 @@ func main() {
 		logrus.Fatalf("error initializing config: %s", err.Error())
 	}
 
-	db, err := repository.NewPostgresDB(repository.Config{
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		Username: os.Getenv("DB_USER"),
-		DBName:   os.Getenv("DB_NAME"),
-		SSLMode:  viper.GetString("db.sslmode"),
-		Password: os.Getenv("DB_PASSWORD"),
-	})
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			logrus.Fatalf("migrate: %s", err.Error())
+		}
+		return
+	}
+
+	repos, closeStorage, err := repository.NewRepository(storageConfig())
 	if err != nil {
 		logrus.Fatalf("failed to initialize db: %s", err.Error())
 	}
-	repos := repository.NewRepository(db)
-	services := service.NewService(repos)
+
+	var keyConfigs []service.KeyConfig
+	if err := viper.UnmarshalKey("auth.keys", &keyConfigs); err != nil {
+		logrus.Fatalf("error reading signing keys: %s", err.Error())
+	}
+
+	keys, err := service.NewKeySet(viper.GetString("auth.signing_key"), keyConfigs)
+	if err != nil {
+		logrus.Fatalf("failed to load signing keys: %s", err.Error())
+	}
+
+	services := service.NewService(repos, keys)
 	handlers := handler.NewHandler(services)
 
-	srv := new(todolist_app.Server)
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	go service.RunTrashPurge(purgeCtx, services.Trash,
+		viper.GetDuration("trash.purge_interval"), viper.GetDuration("trash.retention"))
+
+	srv := todolist_app.NewServer(viper.GetString("port"), handlers.InitRoutes())
 	go func() {
-		if err := srv.Run(viper.GetString("port"), handlers.InitRoutes()); err != nil {
+		if err := srv.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logrus.Fatalf("error occured while running http server: %s", err.Error())
 		}
 	}()
@@ -61,15 +78,35 @@ func main() {
 
 	logrus.Print("TodoApp Shutting Down")
 
-	if err := srv.Shutdown(context.Background()); err != nil {
+	stopPurge()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), viper.GetDuration("shutdown_timeout"))
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logrus.Errorf("error occured on server shutting down: %s", err.Error())
 	}
 
-	if err := db.Close(); err != nil {
+	if err := closeStorage(); err != nil {
 		logrus.Errorf("error occured on db connection close: %s", err.Error())
 	}
 }
 
+func storageConfig() repository.Config {
+	return repository.Config{
+		Driver:       viper.GetString("storage.driver"),
+		Host:         os.Getenv("DB_HOST"),
+		Port:         os.Getenv("DB_PORT"),
+		Username:     os.Getenv("DB_USER"),
+		DBName:       os.Getenv("DB_NAME"),
+		SSLMode:      viper.GetString("db.sslmode"),
+		Password:     os.Getenv("DB_PASSWORD"),
+		Path:         viper.GetString("storage.sqlite_path"),
+		AutoMigrate:  viper.GetBool("db.auto_migrate"),
+		QueryTimeout: viper.GetDuration("db.query_timeout"),
+	}
+}
+
 func initConfig() error {
 	viper.AddConfigPath("configs")
 	viper.SetConfigName("config")
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"todolist-app/pkg/repository"
+)
+
+const migrateUsage = "usage: todo-app migrate up | down [steps] | status | force <version>"
+
+// runMigrate runs the migrate subcommand on the configured storage.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m, err := repository.OpenMigrator(storageConfig())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := m.Up()
+		fmt.Printf("applied %d migrations\n", applied)
+		return err
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number, got %q", args[1])
+			}
+		}
+
+		undone, err := m.Down(steps)
+		fmt.Printf("undid %d migrations\n", undone)
+		return err
+	case args[0] == "status" && len(args) == 1:
+		status, err := m.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range status.Migrations {
+			state := "pending"
+			if migration.Version <= status.Version {
+				state = "applied"
+			}
+			if migration.Version == status.Version && status.Dirty {
+				state = "dirty"
+			}
+			fmt.Printf("%06d_%s\t%s\n", migration.Version, migration.Name, state)
+		}
+		return nil
+	case args[0] == "force" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("version must be a migration number or 0, got %q", args[1])
+		}
+
+		return m.Force(version)
+	}
+
+	return errors.New(migrateUsage)
+}
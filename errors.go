@@ -0,0 +1,68 @@
+package todolist_app
+
+import "errors"
+
+type ErrorCode string
+
+const (
+	CodeNotFound     ErrorCode = "not_found"
+	CodeConflict     ErrorCode = "conflict"
+	CodeForbidden    ErrorCode = "forbidden"
+	CodeValidation   ErrorCode = "validation_failed"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	// CodePreconditionFailed reports a write based on an outdated version of a record.
+	CodePreconditionFailed ErrorCode = "precondition_failed"
+)
+
+// Error is a domain error. Message is safe to show to API clients, Err keeps the underlying cause for logs.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NewNotFoundError(message string) error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+func NewConflictError(message string) error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+func NewForbiddenError(message string) error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+func NewValidationError(message string) error {
+	return &Error{Code: CodeValidation, Message: message}
+}
+
+func NewUnauthorizedError(message string) error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+func NewPreconditionFailedError(message string) error {
+	return &Error{Code: CodePreconditionFailed, Message: message}
+}
+
+// ErrorCodeOf returns the code of the domain error in err's chain, or an empty code if there is none.
+func ErrorCodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return ""
+}
@@ -0,0 +1,34 @@
+package todolist_app
+
+import "time"
+
+// Label is a personal tag. Labels belong to one user and are only visible to them,
+// even when they are attached to items of a shared list.
+type Label struct {
+	Id        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name" binding:"required"`
+	Color     string    `json:"color" db:"color"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateLabelInput struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+func (i UpdateLabelInput) Validate() error {
+	if i.Name == nil && i.Color == nil {
+		return NewValidationError("update structure has no values")
+	}
+
+	if i.Name != nil && *i.Name == "" {
+		return NewValidationError("name must not be empty")
+	}
+
+	return nil
+}
+
+type AttachLabelInput struct {
+	LabelId int `json:"label_id" binding:"required"`
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	todolist_app "todolist-app"
+)
+
+type getActivityResponse struct {
+	Data       []todolist_app.Activity `json:"data"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// @Summary      Get List Activity
+// @Security     ApiKeyAuth
+// @Tags         activity
+// @Description  get who changed what in a list and its items, newest first
+// @ID           get-list-activity
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int     true   "List ID"
+// @Param        limit   query     int     false  "Page size (max 100)"
+// @Param        cursor  query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort    query     string  false  "Sort field: id (default -id) or created_at, prefix with - for descending"
+// @Success      200     {object}  getActivityResponse  "Activity entries"
+// @Failure      400     {object}  errorResponse        "Bad Request"
+// @Failure      404     {object}  errorResponse        "Not Found"
+// @Failure      500     {object}  errorResponse        "Internal Server Error"
+// @Router       /api/lists/{id}/activity [get]
+func (h *Handler) getListActivity(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	var page todolist_app.Page
+	if err := c.ShouldBindQuery(&page); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	activity, next, err := h.services.Activity.GetByList(c.Request.Context(), userId, listId, page)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getActivityResponse{
+		Data:       activity,
+		NextCursor: next,
+	})
+}
+
+// @Summary      Get Item History
+// @Security     ApiKeyAuth
+// @Tags         activity
+// @Description  get the changes of an item across the lists it has been in that the user is a member of, newest first
+// @ID           get-item-history
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int     true   "Item ID"
+// @Param        limit   query     int     false  "Page size (max 100)"
+// @Param        cursor  query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort    query     string  false  "Sort field: id (default -id) or created_at, prefix with - for descending"
+// @Success      200     {object}  getActivityResponse  "Activity entries"
+// @Failure      400     {object}  errorResponse        "Bad Request"
+// @Failure      404     {object}  errorResponse        "Not Found"
+// @Failure      500     {object}  errorResponse        "Internal Server Error"
+// @Router       /api/items/{id}/history [get]
+func (h *Handler) getItemHistory(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var page todolist_app.Page
+	if err := c.ShouldBindQuery(&page); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	activity, next, err := h.services.Activity.GetByItem(c.Request.Context(), userId, itemId, page)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getActivityResponse{
+		Data:       activity,
+		NextCursor: next,
+	})
+}
@@ -11,6 +11,10 @@ type signInInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // @Summary		SignUp
 // @Tags			auth
 // @Description	create account
@@ -20,6 +24,7 @@ type signInInput struct {
 // @Param			input	body	todolist_app.User	true	"account info"
 // @Success		200		{integer}	integer		1
 // @Failure		400,404	{object}	errorResponse
+// @Failure		409		{object}	errorResponse	"username is already taken"
 // @Failure		500		{object}	errorResponse
 // @Failure		default	{object}	errorResponse
 // @Router			/auth/sign-up [post]
@@ -31,9 +36,9 @@ func (h *Handler) signUp(c *gin.Context) {
 		return
 	}
 
-	id, err := h.services.Authorization.CreateUser(input)
+	id, err := h.services.Authorization.CreateUser(c.Request.Context(), input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
@@ -48,9 +53,10 @@ func (h *Handler) signUp(c *gin.Context) {
 // @ID				login
 // @Accept			json
 // @Produce		json
-// @Param			input	body		signInInput	true	"credentials"
-// @Success		200		{string}	string		"token"
+// @Param			input	body		signInInput			true	"credentials"
+// @Success		200		{object}	todolist_app.Tokens	"access and refresh tokens"
 // @Failure		400,404	{object}	errorResponse
+// @Failure		401		{object}	errorResponse	"invalid username or password"
 // @Failure		500		{object}	errorResponse
 // @Failure		default	{object}	errorResponse
 // @Router			/auth/sign-in [post]
@@ -62,13 +68,79 @@ func (h *Handler) signIn(c *gin.Context) {
 		return
 	}
 
-	token, err := h.services.Authorization.GenerateToken(input.Username, input.Password)
+	tokens, err := h.services.Authorization.GenerateTokens(c.Request.Context(), input.Username, input.Password)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"token": token,
-	})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary		Refresh
+// @Tags			auth
+// @Description	exchange a refresh token for a new token pair, the used refresh token becomes invalid
+// @ID				refresh
+// @Accept			json
+// @Produce		json
+// @Param			input	body		refreshInput		true	"refresh token"
+// @Success		200		{object}	todolist_app.Tokens	"access and refresh tokens"
+// @Failure		400		{object}	errorResponse
+// @Failure		401		{object}	errorResponse
+// @Failure		default	{object}	errorResponse
+// @Router			/auth/refresh [post]
+func (h *Handler) refresh(c *gin.Context) {
+	var input refreshInput
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := h.services.Authorization.RefreshTokens(c.Request.Context(), input.RefreshToken)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary		Logout
+// @Tags			auth
+// @Description	revoke the session of a refresh token together with its access tokens
+// @ID				logout
+// @Accept			json
+// @Produce		json
+// @Param			input	body		refreshInput	true	"refresh token"
+// @Success		200		{object}	statusResponse
+// @Failure		400		{object}	errorResponse
+// @Failure		401		{object}	errorResponse
+// @Failure		default	{object}	errorResponse
+// @Router			/auth/logout [post]
+func (h *Handler) logout(c *gin.Context) {
+	var input refreshInput
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.Authorization.Logout(c.Request.Context(), input.RefreshToken); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary		JWKS
+// @Tags			auth
+// @Description	public keys for verifying access tokens signed with asymmetric algorithms
+// @ID				jwks
+// @Produce		json
+// @Success		200	{object}	service.JWKSet
+// @Router			/.well-known/jwks.json [get]
+func (h *Handler) jwks(c *gin.Context) {
+	c.JSON(http.StatusOK, h.services.Authorization.JWKS())
 }
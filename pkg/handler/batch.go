@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	todolist_app "todolist-app"
+)
+
+type batchResponse struct {
+	Results []todolist_app.BatchResult `json:"results"`
+}
+
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+// @Summary      Batch Item Operations
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  create, update and delete items of a list in one transaction. In atomic mode (default) a failing operation rolls back the batch, in partial mode the other operations are kept and the failure is reported in its result
+// @ID           batch-items
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                      true  "List ID"
+// @Param        input  body      todolist_app.BatchInput  true  "Operations and mode (atomic or partial)"
+// @Success      200    {object}  batchResponse            "Result of every operation"
+// @Failure      400    {object}  errorResponse            "Invalid operations"
+// @Failure      401    {object}  errorResponse            "Authentication error"
+// @Failure      403    {object}  errorResponse            "Viewers cannot change items"
+// @Failure      404    {object}  errorResponse            "List or item not found"
+// @Failure      500    {object}  errorResponse            "Internal server error"
+// @Router       /api/lists/{id}/items/batch [post]
+func (h *Handler) batchItems(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	var input todolist_app.BatchInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := h.services.TodoItem.Batch(c.Request.Context(), userId, listId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batchResponse{Results: results})
+}
+
+// @Summary      Complete All Items
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  mark every item of the list as done
+// @ID           complete-all-items
+// @Produce      json
+// @Param        id   path      int            true  "List ID"
+// @Success      200  {object}  countResponse  "Number of completed items"
+// @Failure      400  {object}  errorResponse  "Invalid list ID parameter"
+// @Failure      401  {object}  errorResponse  "Authentication error"
+// @Failure      403  {object}  errorResponse  "Viewers cannot change items"
+// @Failure      404  {object}  errorResponse  "Todo list not found"
+// @Failure      500  {object}  errorResponse  "Internal server error"
+// @Router       /api/lists/{id}/items/complete-all [post]
+func (h *Handler) completeAllItems(c *gin.Context) {
+	h.countItems(c, h.services.TodoItem.CompleteAll)
+}
+
+// @Summary      Uncheck All Items
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  mark every item of the list as not done
+// @ID           uncheck-all-items
+// @Produce      json
+// @Param        id   path      int            true  "List ID"
+// @Success      200  {object}  countResponse  "Number of unchecked items"
+// @Failure      400  {object}  errorResponse  "Invalid list ID parameter"
+// @Failure      401  {object}  errorResponse  "Authentication error"
+// @Failure      403  {object}  errorResponse  "Viewers cannot change items"
+// @Failure      404  {object}  errorResponse  "Todo list not found"
+// @Failure      500  {object}  errorResponse  "Internal server error"
+// @Router       /api/lists/{id}/items/uncheck-all [post]
+func (h *Handler) uncheckAllItems(c *gin.Context) {
+	h.countItems(c, h.services.TodoItem.UncheckAll)
+}
+
+// @Summary      Clear Completed Items
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  move the done items of the list and their subtasks to the trash
+// @ID           clear-completed-items
+// @Produce      json
+// @Param        id   path      int            true  "List ID"
+// @Success      200  {object}  countResponse  "Number of removed items"
+// @Failure      400  {object}  errorResponse  "Invalid list ID parameter"
+// @Failure      401  {object}  errorResponse  "Authentication error"
+// @Failure      403  {object}  errorResponse  "Viewers cannot change items"
+// @Failure      404  {object}  errorResponse  "Todo list not found"
+// @Failure      500  {object}  errorResponse  "Internal server error"
+// @Router       /api/lists/{id}/items/clear-completed [post]
+func (h *Handler) clearCompletedItems(c *gin.Context) {
+	h.countItems(c, h.services.TodoItem.ClearCompleted)
+}
+
+// countItems runs a list-wide shortcut and responds with the number of items it changed.
+func (h *Handler) countItems(c *gin.Context, run func(ctx context.Context, userId, listId int) (int, error)) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	count, err := run(c.Request.Context(), userId, listId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, countResponse{Count: count})
+}
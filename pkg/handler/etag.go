@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The ETag of a list or an item is its version, which grows with every update.
+
+func etag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatchVersion returns the version required by the If-Match header, or nil if any version will do.
+func ifMatchVersion(c *gin.Context) (*int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header %q", header)
+	}
+
+	return &version, nil
+}
+
+// notModified sets the ETag header and answers 304 Not Modified when it matches If-None-Match.
+func notModified(c *gin.Context, version int) bool {
+	tag := etag(version)
+	c.Header("ETag", tag)
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
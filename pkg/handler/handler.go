@@ -21,11 +21,14 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
 	router.Use(prometheusMiddleware())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", h.jwks)
 
 	auth := router.Group("/auth")
 	{
 		auth.POST("/sign-up", h.signUp)
 		auth.POST("/sign-in", h.signIn)
+		auth.POST("/refresh", h.refresh)
+		auth.POST("/logout", h.logout)
 	}
 
 	api := router.Group("/api", h.userIdentity)
@@ -42,13 +45,57 @@ func (h *Handler) InitRoutes() *gin.Engine {
 			{
 				items.POST("/", h.createItem)
 				items.GET("/", h.getAllItems)
+				items.PATCH("/order", h.reorderItems)
+				items.POST("/batch", h.batchItems)
+				items.POST("/complete-all", h.completeAllItems)
+				items.POST("/uncheck-all", h.uncheckAllItems)
+				items.POST("/clear-completed", h.clearCompletedItems)
 			}
+
+			members := lists.Group(":id/members")
+			{
+				members.POST("/", h.addMember)
+				members.GET("/", h.getMembers)
+				members.DELETE("/:userId", h.deleteMember)
+			}
+
+			lists.GET("/:id/activity", h.getListActivity)
 		}
 		items := api.Group("items")
 		{
+			items.GET("/", h.getItems)
 			items.GET("/:id", h.getItemById)
 			items.PUT("/:id", h.updateItem)
 			items.DELETE("/:id", h.deleteItem)
+			items.PATCH("/:id/position", h.setItemPosition)
+			items.POST("/:id/move", h.moveItem)
+			items.POST("/:id/subtasks", h.createSubtask)
+			items.GET("/:id/subtasks", h.getSubtasks)
+			items.PUT("/:id/series", h.updateSeries)
+			items.DELETE("/:id/series", h.stopSeries)
+			items.GET("/:id/history", h.getItemHistory)
+			items.GET("/:id/labels", h.getItemLabels)
+			items.POST("/:id/labels", h.attachLabel)
+			items.DELETE("/:id/labels/:labelId", h.detachLabel)
+		}
+
+		api.GET("/views/:view", h.getView)
+		api.GET("/search", h.search)
+
+		trash := api.Group("/trash")
+		{
+			trash.GET("/", h.getTrash)
+			trash.POST("/lists/:id/restore", h.restoreList)
+			trash.POST("/items/:id/restore", h.restoreItem)
+		}
+
+		labels := api.Group("/labels")
+		{
+			labels.POST("/", h.createLabel)
+			labels.GET("/", h.getAllLabels)
+			labels.GET("/:id", h.getLabelById)
+			labels.PUT("/:id", h.updateLabel)
+			labels.DELETE("/:id", h.deleteLabel)
 		}
 	}
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
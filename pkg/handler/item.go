@@ -7,6 +7,11 @@ import (
 	todolist_app "todolist-app"
 )
 
+type getAllItemsResponse struct {
+	Data       []todolist_app.TodoItem `json:"data"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
 // @Summary      Create todo items
 // @Security     ApiKeyAuth
 // @Tags         items
@@ -19,6 +24,7 @@ import (
 // @Success      200    {object} map[string]int          "ID of the created item"
 // @Failure      400    {object} errorResponse           "Invalid list ID parameter or bad request data"
 // @Failure      401    {object} errorResponse           "Authentication error"
+// @Failure      403    {object} errorResponse           "Viewers cannot add items"
 // @Failure      404    {object} errorResponse           "Todo list not found"
 // @Failure      500    {object} errorResponse           "Internal server error"
 // @Router       /api/lists/{id}/items [post]
@@ -40,9 +46,9 @@ func (h *Handler) createItem(c *gin.Context) {
 		return
 	}
 
-	id, err := h.services.TodoItem.Create(userId, listId, input)
+	id, err := h.services.TodoItem.Create(c.Request.Context(), userId, listId, input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
@@ -54,12 +60,18 @@ func (h *Handler) createItem(c *gin.Context) {
 // @Summary      Get All Items
 // @Security     ApiKeyAuth
 // @Tags         items
-// @Description  get all items for a specified list
+// @Description  get all top-level items for a specified list, subtasks are listed under their parent
 // @ID           get-all-items
 // @Accept       json
 // @Produce      json
-// @Param        id  path      int  true  "List ID"
-// @Success      200     {object}  []todolist_app.TodoItem  "List of Todo Items"
+// @Param        id      path      int     true   "List ID"
+// @Param        limit   query     int     false  "Page size (max 100)"
+// @Param        cursor  query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort    query     string  false  "Sort field: position (default), id, title, created_at, updated_at, completed_at, done, due_at or priority, prefix with - for descending"
+// @Param        done    query     bool    false  "Only done or undone items"
+// @Param        q       query     string  false  "Substring of the title or description"
+// @Param        label   query     int     false  "Only items with this label"
+// @Success      200     {object}  getAllItemsResponse      "List of Todo Items"
 // @Failure      400     {object}  errorResponse            "Bad Request"
 // @Failure      404     {object}  errorResponse            "Not Found"
 // @Failure      500     {object}  errorResponse            "Internal Server Error"
@@ -77,13 +89,67 @@ func (h *Handler) getAllItems(c *gin.Context) {
 		return
 	}
 
-	items, err := h.services.TodoItem.GetAll(userId, listId)
+	var filter todolist_app.ItemFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, next, err := h.services.TodoItem.GetAll(c.Request.Context(), userId, listId, filter)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllItemsResponse{
+		Data:       items,
+		NextCursor: next,
+	})
+}
+
+// @Summary      Get Items Across Lists
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  get items from every list of the user filtered by due date or label
+// @ID           get-items-by-filter
+// @Accept       json
+// @Produce      json
+// @Param        due_before  query     string  false  "Only items due before this time (RFC 3339)"
+// @Param        due_after   query     string  false  "Only items due after this time (RFC 3339)"
+// @Param        overdue     query     bool    false  "Only undone items whose due date has passed"
+// @Param        limit       query     int     false  "Page size (max 100)"
+// @Param        cursor      query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort        query     string  false  "Sort field: id, title, created_at, updated_at, completed_at, done, due_at or priority, prefix with - for descending"
+// @Param        done        query     bool    false  "Only done or undone items"
+// @Param        q           query     string  false  "Substring of the title or description"
+// @Param        label       query     int     false  "Only items with this label"
+// @Success      200     {object}  getAllItemsResponse      "List of Todo Items"
+// @Failure      400     {object}  errorResponse            "Bad Request"
+// @Failure      500     {object}  errorResponse            "Internal Server Error"
+// @Failure      default {object}  errorResponse            "Default Error"
+// @Router       /api/items [get]
+func (h *Handler) getItems(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var filter todolist_app.ItemFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, next, err := h.services.TodoItem.GetAllByUser(c.Request.Context(), userId, filter)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	c.JSON(http.StatusOK, getAllItemsResponse{
+		Data:       items,
+		NextCursor: next,
+	})
 }
 
 // @Summary      Get Items By Id
@@ -93,8 +159,10 @@ func (h *Handler) getAllItems(c *gin.Context) {
 // @ID           get-item-by-id
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Item ID"
+// @Param        id             path      int     true   "Item ID"
+// @Param        If-None-Match  header    string  false  "ETag of a cached copy"
 // @Success      200  {object}  todolist_app.ListItem  "The requested Todo Item"
+// @Success      304  "The cached copy is up to date"
 // @Failure      400  {object}  errorResponse           "Bad Request"
 // @Failure      404  {object}  errorResponse           "Not Found"
 // @Failure      500  {object}  errorResponse           "Internal Server Error"
@@ -112,9 +180,13 @@ func (h *Handler) getItemById(c *gin.Context) {
 		return
 	}
 
-	item, err := h.services.TodoItem.GetById(userId, itemId)
+	item, err := h.services.TodoItem.GetById(c.Request.Context(), userId, itemId)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	if notModified(c, item.Version) {
 		return
 	}
 
@@ -122,6 +194,86 @@ func (h *Handler) getItemById(c *gin.Context) {
 
 }
 
+// @Summary      Create Subtask
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  add a checklist item under an item, subtasks live in the list of their parent
+// @ID           create-subtask
+// @Accept       json
+// @Produce      json
+// @Param        id     path    int                      true  "Parent Item ID"
+// @Param        input  body    todolist_app.TodoItem    true  "Subtask Info"
+// @Success      200    {object} map[string]int          "ID of the created subtask"
+// @Failure      400    {object} errorResponse           "Bad Request"
+// @Failure      403    {object} errorResponse           "Forbidden"
+// @Failure      404    {object} errorResponse           "Not Found"
+// @Failure      500    {object} errorResponse           "Internal Server Error"
+// @Router       /api/items/{id}/subtasks [post]
+func (h *Handler) createSubtask(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	parentId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todolist_app.TodoItem
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := h.services.TodoItem.CreateSubtask(c.Request.Context(), userId, parentId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+// @Summary      Get Subtasks
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  get the subtasks of an item in list order
+// @ID           get-subtasks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Parent Item ID"
+// @Success      200  {object}  getAllItemsResponse  "List of Subtasks"
+// @Failure      400  {object}  errorResponse        "Bad Request"
+// @Failure      404  {object}  errorResponse        "Not Found"
+// @Failure      500  {object}  errorResponse        "Internal Server Error"
+// @Router       /api/items/{id}/subtasks [get]
+func (h *Handler) getSubtasks(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	parentId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	items, err := h.services.TodoItem.GetSubtasks(c.Request.Context(), userId, parentId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllItemsResponse{
+		Data: items,
+	})
+}
+
 // @Summary     Update Item
 // @Security    ApiKeyAuth
 // @Tags        items
@@ -130,9 +282,13 @@ func (h *Handler) getItemById(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id   path      int  true  "Item ID"
+// @Param       input body     todolist_app.UpdateItemInput true "Update data, due_at or remind_at set to null is cleared"
+// @Param       If-Match header string false "ETag the update is based on"
 // @Success     200  {object}  todolist_app.ListItem
 // @Failure     400  {object}  errorResponse
+// @Failure     403  {object}  errorResponse
 // @Failure     404  {object}  errorResponse
+// @Failure     412  {object}  errorResponse
 // @Failure     500  {object}  errorResponse
 // @Failure     default {object}  errorResponse
 // @Router      /api/items/{id} [put]
@@ -154,8 +310,88 @@ func (h *Handler) updateItem(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.TodoItem.Update(userId, id, input); err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+	if input.Version, err = ifMatchVersion(c); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.TodoItem.Update(c.Request.Context(), userId, id, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Update Series
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  change every undone item of the item's recurring series, setting recurrence on an item that does not repeat starts a series
+// @ID           update-series
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                             true  "Item ID"
+// @Param        input  body      todolist_app.UpdateSeriesInput  true  "Update data, recurrence is an RRULE such as FREQ=WEEKLY;BYDAY=MO"
+// @Success      200    {object}  statusResponse  "Success Response"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      403    {object}  errorResponse   "Forbidden"
+// @Failure      404    {object}  errorResponse   "Not Found"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/items/{id}/series [put]
+func (h *Handler) updateSeries(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todolist_app.UpdateSeriesInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.TodoItem.UpdateSeries(c.Request.Context(), userId, itemId, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Stop Series
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  stop the item's recurring series, its items are kept but no longer repeat
+// @ID           stop-series
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Item ID"
+// @Success      200  {object}  statusResponse  "Success Response"
+// @Failure      400  {object}  errorResponse   "Bad Request"
+// @Failure      403  {object}  errorResponse   "Forbidden"
+// @Failure      404  {object}  errorResponse   "Not Found"
+// @Failure      500  {object}  errorResponse   "Internal Server Error"
+// @Router       /api/items/{id}/series [delete]
+func (h *Handler) stopSeries(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.TodoItem.StopSeries(c.Request.Context(), userId, itemId); err != nil {
+		newServiceErrorResponse(c, err)
 		return
 	}
 
@@ -165,13 +401,14 @@ func (h *Handler) updateItem(c *gin.Context) {
 // @Summary      Delete Items
 // @Security     ApiKeyAuth
 // @Tags         items
-// @Description  delete item by id
+// @Description  move an item and its subtasks to the trash
 // @ID           delete-item-by-id
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "Item ID"
 // @Success      200  {object}  statusResponse          "Success Response"
 // @Failure      400  {object}  errorResponse           "Bad Request"
+// @Failure      403  {object}  errorResponse           "Forbidden"
 // @Failure      404  {object}  errorResponse           "Not Found"
 // @Failure      500  {object}  errorResponse           "Internal Server Error"
 // @Failure      default {object}  errorResponse        "Default Error"
@@ -188,9 +425,132 @@ func (h *Handler) deleteItem(c *gin.Context) {
 		return
 	}
 
-	err = h.services.TodoItem.Delete(userId, itemId)
+	err = h.services.TodoItem.Delete(c.Request.Context(), userId, itemId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Reorder Items
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  put items of a list in the given order, items left out keep their order after the listed ones
+// @ID           reorder-items
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                             true  "List ID"
+// @Param        input  body      todolist_app.ReorderItemsInput  true  "Item IDs in the new order"
+// @Success      200    {object}  statusResponse  "Success Response"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      403    {object}  errorResponse   "Forbidden"
+// @Failure      404    {object}  errorResponse   "Not Found"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/lists/{id}/items/order [patch]
+func (h *Handler) reorderItems(c *gin.Context) {
+	userId, err := getUserId(c)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	var input todolist_app.ReorderItemsInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.TodoItem.Reorder(c.Request.Context(), userId, listId, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Move Item Within List
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  place an item right before or right after another item of the same list
+// @ID           set-item-position
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                             true  "Item ID"
+// @Param        input  body      todolist_app.ItemPositionInput  true  "Either before_id or after_id"
+// @Success      200    {object}  statusResponse  "Success Response"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      403    {object}  errorResponse   "Forbidden"
+// @Failure      404    {object}  errorResponse   "Not Found"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/items/{id}/position [patch]
+func (h *Handler) setItemPosition(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todolist_app.ItemPositionInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.TodoItem.SetPosition(c.Request.Context(), userId, itemId, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Move Item To Another List
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  move an item to another list keeping its id, requires write access to both lists
+// @ID           move-item
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                         true  "Item ID"
+// @Param        input  body      todolist_app.MoveItemInput  true  "Destination list"
+// @Success      200    {object}  statusResponse  "Success Response"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      403    {object}  errorResponse   "Forbidden"
+// @Failure      404    {object}  errorResponse   "Not Found"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/items/{id}/move [post]
+func (h *Handler) moveItem(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todolist_app.MoveItemInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.TodoItem.Move(c.Request.Context(), userId, itemId, input); err != nil {
+		newServiceErrorResponse(c, err)
 		return
 	}
 
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	todolist_app "todolist-app"
+)
+
+type getAllLabelsResponse struct {
+	Data []todolist_app.Label `json:"data"`
+}
+
+// @Summary      Create Label
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  create a personal label
+// @ID           create-label
+// @Accept       json
+// @Produce      json
+// @Param        input  body      todolist_app.Label  true  "Label Info"
+// @Success      200    {object}  map[string]int      "ID of the created label"
+// @Failure      400    {object}  errorResponse       "Bad Request"
+// @Failure      409    {object}  errorResponse       "Label with this name already exists"
+// @Failure      500    {object}  errorResponse       "Internal Server Error"
+// @Router       /api/labels [post]
+func (h *Handler) createLabel(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var input todolist_app.Label
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := h.services.Label.Create(c.Request.Context(), userId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+// @Summary      Get All Labels
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  get the labels of the user sorted by name
+// @ID           get-all-labels
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  getAllLabelsResponse  "List of Labels"
+// @Failure      500  {object}  errorResponse         "Internal Server Error"
+// @Router       /api/labels [get]
+func (h *Handler) getAllLabels(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	labels, err := h.services.Label.GetAll(c.Request.Context(), userId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllLabelsResponse{
+		Data: labels,
+	})
+}
+
+// @Summary      Get Label By Id
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  get label by id
+// @ID           get-label-by-id
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Label ID"
+// @Success      200  {object}  todolist_app.Label  "The requested Label"
+// @Failure      400  {object}  errorResponse       "Bad Request"
+// @Failure      404  {object}  errorResponse       "Not Found"
+// @Failure      500  {object}  errorResponse       "Internal Server Error"
+// @Router       /api/labels/{id} [get]
+func (h *Handler) getLabelById(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	labelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	label, err := h.services.Label.GetById(c.Request.Context(), userId, labelId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// @Summary      Update Label
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  rename or recolor a label
+// @ID           update-label
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                            true  "Label ID"
+// @Param        input  body      todolist_app.UpdateLabelInput  true  "Update data"
+// @Success      200    {object}  statusResponse  "Success Response"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      404    {object}  errorResponse   "Not Found"
+// @Failure      409    {object}  errorResponse   "Label with this name already exists"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/labels/{id} [put]
+func (h *Handler) updateLabel(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	labelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todolist_app.UpdateLabelInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.Label.Update(c.Request.Context(), userId, labelId, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Delete Label
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  delete a label and detach it from all items
+// @ID           delete-label
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Label ID"
+// @Success      200  {object}  statusResponse  "Success Response"
+// @Failure      400  {object}  errorResponse   "Bad Request"
+// @Failure      404  {object}  errorResponse   "Not Found"
+// @Failure      500  {object}  errorResponse   "Internal Server Error"
+// @Router       /api/labels/{id} [delete]
+func (h *Handler) deleteLabel(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	labelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Label.Delete(c.Request.Context(), userId, labelId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Get Item Labels
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  get the labels of the user attached to an item
+// @ID           get-item-labels
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Item ID"
+// @Success      200  {object}  getAllLabelsResponse  "List of Labels"
+// @Failure      400  {object}  errorResponse         "Bad Request"
+// @Failure      404  {object}  errorResponse         "Not Found"
+// @Failure      500  {object}  errorResponse         "Internal Server Error"
+// @Router       /api/items/{id}/labels [get]
+func (h *Handler) getItemLabels(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	labels, err := h.services.Label.GetByItem(c.Request.Context(), userId, itemId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllLabelsResponse{
+		Data: labels,
+	})
+}
+
+// @Summary      Attach Label
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  attach one of the user's labels to an item
+// @ID           attach-label
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                            true  "Item ID"
+// @Param        input  body      todolist_app.AttachLabelInput  true  "Label to attach"
+// @Success      200    {object}  statusResponse  "Success Response"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      404    {object}  errorResponse   "Item or label not found"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/items/{id}/labels [post]
+func (h *Handler) attachLabel(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	var input todolist_app.AttachLabelInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.Label.Attach(c.Request.Context(), userId, itemId, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Detach Label
+// @Security     ApiKeyAuth
+// @Tags         labels
+// @Description  remove a label from an item
+// @ID           detach-label
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int  true  "Item ID"
+// @Param        labelId  path      int  true  "Label ID"
+// @Success      200      {object}  statusResponse  "Success Response"
+// @Failure      400      {object}  errorResponse   "Bad Request"
+// @Failure      404      {object}  errorResponse   "Not Found"
+// @Failure      500      {object}  errorResponse   "Internal Server Error"
+// @Router       /api/items/{id}/labels/{labelId} [delete]
+func (h *Handler) detachLabel(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	labelId, err := strconv.Atoi(c.Param("labelId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid label id param")
+		return
+	}
+
+	if err := h.services.Label.Detach(c.Request.Context(), userId, itemId, labelId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
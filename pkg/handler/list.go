@@ -8,7 +8,8 @@ import (
 )
 
 type getAllListsResponse struct {
-	Data []todolist_app.TodoList `json:"data"`
+	Data       []todolist_app.TodoList `json:"data"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
 }
 
 // @Summary      Create todo list
@@ -37,9 +38,9 @@ func (h *Handler) createList(c *gin.Context) {
 		return
 	}
 
-	id, err := h.services.TodoList.Create(userId, input)
+	id, err := h.services.TodoList.Create(c.Request.Context(), userId, input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -54,7 +55,12 @@ func (h *Handler) createList(c *gin.Context) {
 // @ID           get-all-lists
 // @Accept       json
 // @Produce      json
+// @Param        limit   query    int     false  "Page size (max 100)"
+// @Param        cursor  query    string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort    query    string  false  "Sort field: id, title, created_at or updated_at, prefix with - for descending"
+// @Param        q       query    string  false  "Substring of the title or description"
 // @Success      200 {object} getAllListsResponse "List of all todo lists"
+// @Failure      400 {object} errorResponse      "Invalid query parameters"
 // @Failure      401 {object} errorResponse      "Authentication error"
 // @Failure      500 {object} errorResponse      "Internal server error"
 // @Router       /api/lists [get]
@@ -65,14 +71,21 @@ func (h *Handler) getAllLists(c *gin.Context) {
 		return
 	}
 
-	lists, err := h.services.TodoList.GetAll(userId)
+	var filter todolist_app.ListFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lists, next, err := h.services.TodoList.GetAll(c.Request.Context(), userId, filter)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, getAllListsResponse{
-		Data: lists,
+		Data:       lists,
+		NextCursor: next,
 	})
 }
 
@@ -83,8 +96,10 @@ func (h *Handler) getAllLists(c *gin.Context) {
 // @ID           get-list-by-id
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Todo List ID"
+// @Param        id             path      int     true   "Todo List ID"
+// @Param        If-None-Match  header    string  false  "ETag of a cached copy"
 // @Success      200  {object}  todolist_app.ListItem "Details of the specific todo list"
+// @Success      304  "The cached copy is up to date"
 // @Failure      400  {object}  errorResponse         "Invalid ID parameter"
 // @Failure      401  {object}  errorResponse         "Authentication error"
 // @Failure      404  {object}  errorResponse         "Todo list not found"
@@ -103,9 +118,13 @@ func (h *Handler) getListById(c *gin.Context) {
 		return
 	}
 
-	list, err := h.services.TodoList.GetById(userId, id)
+	list, err := h.services.TodoList.GetById(c.Request.Context(), userId, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	if notModified(c, list.Version) {
 		return
 	}
 
@@ -122,10 +141,13 @@ func (h *Handler) getListById(c *gin.Context) {
 // @Produce      json
 // @Param        id    path      int                        true "Todo List ID"
 // @Param        input body      todolist_app.UpdateListInput true "Update data"
+// @Param        If-Match header string                     false "ETag the update is based on"
 // @Success      200   {object}  statusResponse             "List updated successfully"
 // @Failure      400   {object}  errorResponse              "Invalid request parameters"
 // @Failure      401   {object}  errorResponse              "Authentication error"
+// @Failure      403   {object}  errorResponse              "Viewers cannot update the list"
 // @Failure      404   {object}  errorResponse              "Todo list not found"
+// @Failure      412   {object}  errorResponse              "The list has been modified since the If-Match version"
 // @Failure      500   {object}  errorResponse              "Internal server error"
 // @Router       /api/lists/{id} [put]
 func (h *Handler) updateList(c *gin.Context) {
@@ -146,8 +168,13 @@ func (h *Handler) updateList(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.TodoList.Update(userId, id, input); err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+	if input.Version, err = ifMatchVersion(c); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.services.TodoList.Update(c.Request.Context(), userId, id, input); err != nil {
+		newServiceErrorResponse(c, err)
 		return
 	}
 
@@ -157,7 +184,7 @@ func (h *Handler) updateList(c *gin.Context) {
 // @Summary      Delete List
 // @Security     ApiKeyAuth
 // @Tags         lists
-// @Description  Delete a specific todo list by its ID
+// @Description  Move a todo list and its items to the trash, they can be restored until the trash is purged
 // @ID           delete-list-by-id
 // @Accept       json
 // @Produce      json
@@ -165,6 +192,7 @@ func (h *Handler) updateList(c *gin.Context) {
 // @Success      200   {object}  statusResponse "List deleted successfully"
 // @Failure      400   {object}  errorResponse  "Invalid ID parameter"
 // @Failure      401   {object}  errorResponse  "Authentication error"
+// @Failure      403   {object}  errorResponse  "Only owners can delete the list"
 // @Failure      404   {object}  errorResponse  "Todo list not found"
 // @Failure      500   {object}  errorResponse  "Internal server error"
 // @Router       /api/lists/{id} [delete]
@@ -180,9 +208,9 @@ func (h *Handler) deleteList(c *gin.Context) {
 		return
 	}
 
-	err = h.services.TodoList.Delete(userId, id)
+	err = h.services.TodoList.Delete(c.Request.Context(), userId, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	todolist_app "todolist-app"
+)
+
+type getAllMembersResponse struct {
+	Data []todolist_app.ListMember `json:"data"`
+}
+
+// @Summary      Add List Member
+// @Security     ApiKeyAuth
+// @Tags         members
+// @Description  Share a todo list with another user or change the role of an existing member
+// @ID           add-list-member
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                          true  "Todo List ID"
+// @Param        input  body      todolist_app.AddMemberInput  true  "Username and role (owner, editor or viewer)"
+// @Success      200    {object}  map[string]int               "user_id"
+// @Failure      400    {object}  errorResponse                "Invalid request parameters"
+// @Failure      401    {object}  errorResponse                "Authentication error"
+// @Failure      403    {object}  errorResponse                "Only owners can manage members"
+// @Failure      404    {object}  errorResponse                "Todo list or user not found"
+// @Failure      409    {object}  errorResponse                "List must keep at least one owner"
+// @Failure      500    {object}  errorResponse                "Internal server error"
+// @Router       /api/lists/{id}/members [post]
+func (h *Handler) addMember(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	var input todolist_app.AddMemberInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	memberId, err := h.services.TodoList.SaveMember(c.Request.Context(), userId, listId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"user_id": memberId,
+	})
+}
+
+// @Summary      Get List Members
+// @Security     ApiKeyAuth
+// @Tags         members
+// @Description  Retrieve the users a todo list is shared with and their roles
+// @ID           get-list-members
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Todo List ID"
+// @Success      200  {object}  getAllMembersResponse  "Members of the list"
+// @Failure      400  {object}  errorResponse          "Invalid ID parameter"
+// @Failure      401  {object}  errorResponse          "Authentication error"
+// @Failure      404  {object}  errorResponse          "Todo list not found"
+// @Failure      500  {object}  errorResponse          "Internal server error"
+// @Router       /api/lists/{id}/members [get]
+func (h *Handler) getMembers(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	members, err := h.services.TodoList.GetMembers(c.Request.Context(), userId, listId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllMembersResponse{
+		Data: members,
+	})
+}
+
+// @Summary      Delete List Member
+// @Security     ApiKeyAuth
+// @Tags         members
+// @Description  Stop sharing a todo list with a user, members may also remove themselves
+// @ID           delete-list-member
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int  true  "Todo List ID"
+// @Param        userId  path      int  true  "Member User ID"
+// @Success      200     {object}  statusResponse  "Member removed successfully"
+// @Failure      400     {object}  errorResponse   "Invalid ID parameter"
+// @Failure      401     {object}  errorResponse   "Authentication error"
+// @Failure      403     {object}  errorResponse   "Only owners can remove other members"
+// @Failure      404     {object}  errorResponse   "Todo list not found"
+// @Failure      409     {object}  errorResponse   "List must keep at least one owner"
+// @Failure      500     {object}  errorResponse   "Internal server error"
+// @Router       /api/lists/{id}/members/{userId} [delete]
+func (h *Handler) deleteMember(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	memberId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid user id param")
+		return
+	}
+
+	if err := h.services.TodoList.DeleteMember(c.Request.Context(), userId, listId, memberId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
@@ -1,19 +1,83 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"net/http"
+	todolist_app "todolist-app"
 )
 
 type errorResponse struct {
 	Message string `json:"message"`
+	Code    string `json:"code"`
 }
 
 type statusResponse struct {
 	Status string `json:"status"`
 }
 
+var errorStatuses = map[todolist_app.ErrorCode]int{
+	todolist_app.CodeNotFound:     http.StatusNotFound,
+	todolist_app.CodeConflict:     http.StatusConflict,
+	todolist_app.CodeForbidden:    http.StatusForbidden,
+	todolist_app.CodeValidation:   http.StatusBadRequest,
+	todolist_app.CodeUnauthorized: http.StatusUnauthorized,
+
+	todolist_app.CodePreconditionFailed: http.StatusPreconditionFailed,
+}
+
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
 	logrus.Error(message)
-	c.AbortWithStatusJSON(statusCode, errorResponse{message})
+	c.AbortWithStatusJSON(statusCode, errorResponse{Message: message, Code: errorCode(statusCode)})
+}
+
+// newServiceErrorResponse maps domain errors to their status codes. Any other error is unexpected,
+// so its details are only logged and the client gets a generic internal error.
+func newServiceErrorResponse(c *gin.Context, err error) {
+	switch {
+	case c.Request.Context().Err() != nil:
+		// the client is gone or the server is shutting down, nobody reads the response
+		logrus.Info(err.Error())
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, errorResponse{
+			Message: "request canceled",
+			Code:    errorCode(http.StatusServiceUnavailable),
+		})
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		logrus.Error(err.Error())
+		c.AbortWithStatusJSON(http.StatusGatewayTimeout, errorResponse{
+			Message: "storage did not respond in time",
+			Code:    errorCode(http.StatusGatewayTimeout),
+		})
+		return
+	}
+
+	var e *todolist_app.Error
+	if !errors.As(err, &e) {
+		logrus.Error(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse{
+			Message: "internal server error",
+			Code:    errorCode(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	logrus.Info(err.Error())
+	c.AbortWithStatusJSON(errorStatuses[e.Code], errorResponse{Message: e.Message, Code: string(e.Code)})
+}
+
+func errorCode(statusCode int) string {
+	for code, status := range errorStatuses {
+		if status == statusCode {
+			return string(code)
+		}
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		return "internal_error"
+	}
+
+	return "bad_request"
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	todolist_app "todolist-app"
+)
+
+type searchResponse struct {
+	Data []todolist_app.SearchHit `json:"data"`
+}
+
+// @Summary      Search
+// @Security     ApiKeyAuth
+// @Tags         search
+// @Description  full-text search over the titles and descriptions of the user's lists and items,
+// @Description  hits of both kinds are ranked together and the escaped text has matches wrapped in <mark> tags
+// @ID           search
+// @Accept       json
+// @Produce      json
+// @Param        q      query     string  true   "Search query, supports quoted phrases, OR and -word"
+// @Param        limit  query     int     false  "Number of hits (max 100)"
+// @Success      200    {object}  searchResponse  "Ranked hits"
+// @Failure      400    {object}  errorResponse   "Bad Request"
+// @Failure      500    {object}  errorResponse   "Internal Server Error"
+// @Router       /api/search [get]
+func (h *Handler) search(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var input todolist_app.SearchInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hits, err := h.services.Search.Search(c.Request.Context(), userId, input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, searchResponse{
+		Data: hits,
+	})
+}
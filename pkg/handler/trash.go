@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	todolist_app "todolist-app"
+)
+
+type getTrashResponse struct {
+	Data []todolist_app.TrashEntry `json:"data"`
+}
+
+// @Summary      Get Trash
+// @Security     ApiKeyAuth
+// @Tags         trash
+// @Description  get deleted lists the user owns and deleted items of lists the user can edit, newest first
+// @ID           get-trash
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  getTrashResponse  "Deleted lists and items"
+// @Failure      500  {object}  errorResponse     "Internal Server Error"
+// @Router       /api/trash [get]
+func (h *Handler) getTrash(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	entries, err := h.services.Trash.GetAll(c.Request.Context(), userId)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getTrashResponse{
+		Data: entries,
+	})
+}
+
+// @Summary      Restore List
+// @Security     ApiKeyAuth
+// @Tags         trash
+// @Description  restore a deleted list together with the items deleted with it
+// @ID           restore-list
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "List ID"
+// @Success      200  {object}  statusResponse  "Success Response"
+// @Failure      400  {object}  errorResponse   "Bad Request"
+// @Failure      404  {object}  errorResponse   "Not Found"
+// @Failure      500  {object}  errorResponse   "Internal Server Error"
+// @Router       /api/trash/lists/{id}/restore [post]
+func (h *Handler) restoreList(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid list id param")
+		return
+	}
+
+	if err := h.services.Trash.RestoreList(c.Request.Context(), userId, listId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// @Summary      Restore Item
+// @Security     ApiKeyAuth
+// @Tags         trash
+// @Description  restore a deleted item together with the subtasks deleted with it
+// @ID           restore-item
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Item ID"
+// @Success      200  {object}  statusResponse  "Success Response"
+// @Failure      400  {object}  errorResponse   "Bad Request"
+// @Failure      404  {object}  errorResponse   "Not Found"
+// @Failure      409  {object}  errorResponse   "The list or the parent item is deleted too"
+// @Failure      500  {object}  errorResponse   "Internal Server Error"
+// @Router       /api/trash/items/{id}/restore [post]
+func (h *Handler) restoreItem(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err := h.services.Trash.RestoreItem(c.Request.Context(), userId, itemId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	todolist_app "todolist-app"
+)
+
+// @Summary      Get Smart View
+// @Security     ApiKeyAuth
+// @Tags         items
+// @Description  get items from every list of the user for a smart view:
+// @Description  today (undone, due today or earlier), upcoming (undone, due in the next 7 days),
+// @Description  overdue, high-priority (undone, high or urgent) or completed
+// @ID           get-view
+// @Accept       json
+// @Produce      json
+// @Param        view    path      string  true   "View name"  Enums(today, upcoming, overdue, high-priority, completed)
+// @Param        tz      query     string  false  "IANA time zone for day boundaries, UTC by default"
+// @Param        limit   query     int     false  "Page size (max 100)"
+// @Param        cursor  query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        sort    query     string  false  "Sort field: id, title, created_at, updated_at, completed_at, done, due_at or priority, prefix with - for descending"
+// @Success      200     {object}  getAllItemsResponse  "List of Todo Items"
+// @Failure      400     {object}  errorResponse        "Bad Request"
+// @Failure      404     {object}  errorResponse        "Unknown view"
+// @Failure      500     {object}  errorResponse        "Internal Server Error"
+// @Router       /api/views/{view} [get]
+func (h *Handler) getView(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	var filter todolist_app.ViewFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, next, err := h.services.TodoItem.GetView(c.Request.Context(), userId, todolist_app.View(c.Param("view")), filter)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllItemsResponse{
+		Data:       items,
+		NextCursor: next,
+	})
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	todolist_app "todolist-app"
+)
+
+type ActivityMemory struct {
+	db *MemoryDB
+}
+
+func NewActivityMemory(db *MemoryDB) *ActivityMemory {
+	return &ActivityMemory{db: db}
+}
+
+// GetByList returns the activity of a list and its items, newest first unless the page asks otherwise.
+func (r *ActivityMemory) GetByList(ctx context.Context, listId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	return r.getPage(ctx, func(s *memoryState, a todolist_app.Activity) bool { return a.ListId == listId }, page)
+}
+
+// GetByItem returns the history of an item across the lists it has been in, leaving out
+// the entries of lists the user is not a member of.
+func (r *ActivityMemory) GetByItem(ctx context.Context, userId, itemId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	return r.getPage(ctx, func(s *memoryState, a todolist_app.Activity) bool {
+		_, member := s.members[memoryMember{ListId: a.ListId, UserId: userId}]
+		return a.ItemId != nil && *a.ItemId == itemId && member
+	}, page)
+}
+
+func (r *ActivityMemory) getPage(ctx context.Context, match func(s *memoryState, a todolist_app.Activity) bool,
+	page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	keyset, err := newKeyset(activitySortColumns, page, "-id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	activity := make([]todolist_app.Activity, 0)
+	err = r.db.read(ctx, func(s *memoryState) error {
+		for _, a := range s.activity {
+			if !match(s, a) {
+				continue
+			}
+
+			if a.ActorId != nil {
+				if user, ok := s.users[*a.ActorId]; ok {
+					username := user.Username
+					a.ActorUsername = &username
+				}
+			}
+
+			activity = append(activity, a)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	activity, next := keyset.page(activity, func(a todolist_app.Activity) int { return a.Id })
+
+	return activity, next, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strconv"
+	"time"
+	todolist_app "todolist-app"
+)
+
+var activitySortColumns = map[string]sortColumn[todolist_app.Activity]{
+	"id": {"a.id", "integer", func(a todolist_app.Activity) string { return strconv.Itoa(a.Id) }},
+	"created_at": {"a.created_at", "timestamptz", func(a todolist_app.Activity) string {
+		return a.CreatedAt.Format(time.RFC3339Nano)
+	}},
+}
+
+type ActivityPostgres struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewActivityPostgres(db *sqlx.DB, timeout time.Duration) *ActivityPostgres {
+	return &ActivityPostgres{db: db, timeout: timeout}
+}
+
+// GetByList returns the activity of a list and its items, newest first unless the page asks otherwise.
+func (r *ActivityPostgres) GetByList(ctx context.Context, listId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.getPage(ctx, "a.list_id = $1", []interface{}{listId}, page)
+}
+
+// GetByItem returns the history of an item across the lists it has been in, leaving out
+// the entries of lists the user is not a member of.
+func (r *ActivityPostgres) GetByItem(ctx context.Context, userId, itemId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	condition := fmt.Sprintf("a.item_id = $1 AND a.list_id IN (SELECT list_id FROM %s WHERE user_id = $2)", usersListsTable)
+
+	return r.getPage(ctx, condition, []interface{}{itemId, userId}, page)
+}
+
+func (r *ActivityPostgres) getPage(ctx context.Context, condition string, args []interface{},
+	page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	keyset, err := newKeyset(activitySortColumns, page, "-id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	conditions := condition
+	if cond, condArgs := keyset.where("a.id", len(args)+1); cond != "" {
+		conditions += " AND " + cond
+		args = append(args, condArgs...)
+	}
+
+	activity := make([]todolist_app.Activity, 0)
+	query := fmt.Sprintf(`SELECT a.id, a.list_id, a.item_id, a.actor_id, u.username AS actor_username, a.entity, a.action,
+									a.changes, a.created_at
+								FROM %s a LEFT JOIN %s u on u.id = a.actor_id WHERE %s %s`,
+		activityTable, usersTable, conditions, keyset.orderBy("a.id"))
+	if err := r.db.SelectContext(ctx, &activity, query, args...); err != nil {
+		return nil, "", err
+	}
+
+	activity, next := keyset.next(activity, func(a todolist_app.Activity) int { return a.Id })
+
+	return activity, next, nil
+}
+
+// activityEntry is written by the repositories in the transaction of the change it records.
+type activityEntry struct {
+	ListId  int
+	ItemId  *int
+	ActorId int
+	Entity  string
+	Action  string
+	Changes todolist_app.Changes
+}
+
+func recordActivity(ctx context.Context, tx *sqlx.Tx, entry activityEntry) error {
+	query := fmt.Sprintf(`INSERT INTO %s (list_id, item_id, actor_id, entity, action, changes)
+								VALUES ($1, $2, $3, $4, $5, $6)`, activityTable)
+	_, err := tx.ExecContext(ctx, query, entry.ListId, entry.ItemId, entry.ActorId, entry.Entity, entry.Action, entry.Changes)
+
+	return err
+}
+
+// recordItemsActivity records the same entry for several items, each in its current list.
+func recordItemsActivity(ctx context.Context, tx *sqlx.Tx, itemIds []int, entry activityEntry) error {
+	query := fmt.Sprintf(`INSERT INTO %s (list_id, item_id, actor_id, entity, action, changes)
+								SELECT li.list_id, li.item_id, $2, $3, $4, $5 FROM %s li WHERE li.item_id = ANY($1)`,
+		activityTable, listsItemsTable)
+	_, err := tx.ExecContext(ctx, query, pq.Array(itemIds), entry.ActorId, entry.Entity, entry.Action, entry.Changes)
+
+	return err
+}
+
+// addChange records a field of an update input that differs from the stored value.
+func addChange[T comparable](changes todolist_app.Changes, field string, before T, after *T) {
+	if after != nil && *after != before {
+		changes[field] = todolist_app.Change{Before: before, After: *after}
+	}
+}
+
+// addTimeChange records a time set or cleared by an update that differs from the stored value.
+func addTimeChange(changes todolist_app.Changes, field string, before *time.Time, after *time.Time) {
+	switch {
+	case before == nil && after == nil:
+	case before != nil && after != nil && before.Equal(*after):
+	default:
+		changes[field] = todolist_app.Change{Before: before, After: after}
+	}
+}
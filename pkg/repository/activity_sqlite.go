@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type ActivitySQLite struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewActivitySQLite(db *sqlx.DB, timeout time.Duration) *ActivitySQLite {
+	return &ActivitySQLite{db: db, timeout: timeout}
+}
+
+// GetByList returns the activity of a list and its items, newest first unless the page asks otherwise.
+func (r *ActivitySQLite) GetByList(ctx context.Context, listId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.getPage(ctx, "a.list_id = ?", []interface{}{listId}, page)
+}
+
+// GetByItem returns the history of an item across the lists it has been in, leaving out
+// the entries of lists the user is not a member of.
+func (r *ActivitySQLite) GetByItem(ctx context.Context, userId, itemId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	condition := fmt.Sprintf("a.item_id = ? AND a.list_id IN (SELECT list_id FROM %s WHERE user_id = ?)", usersListsTable)
+
+	return r.getPage(ctx, condition, []interface{}{itemId, userId}, page)
+}
+
+func (r *ActivitySQLite) getPage(ctx context.Context, condition string, args []interface{},
+	page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	keyset, err := newKeyset(activitySortColumns, page, "-id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	conditions := condition
+	if cond, condArgs := keyset.sqliteWhere("a.id"); cond != "" {
+		conditions += " AND " + cond
+		args = append(args, condArgs...)
+	}
+
+	activity := make([]todolist_app.Activity, 0)
+	query := fmt.Sprintf(`SELECT a.id, a.list_id, a.item_id, a.actor_id, u.username AS actor_username, a.entity, a.action,
+									a.changes, a.created_at
+								FROM %s a LEFT JOIN %s u on u.id = a.actor_id WHERE %s %s`,
+		activityTable, usersTable, conditions, keyset.orderBy("a.id"))
+	if err := r.db.SelectContext(ctx, &activity, query, args...); err != nil {
+		return nil, "", err
+	}
+
+	activity, next := keyset.next(activity, func(a todolist_app.Activity) int { return a.Id })
+
+	return activity, next, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	todolist_app "todolist-app"
+)
+
+type AuthMemory struct {
+	db *MemoryDB
+}
+
+func NewAuthMemory(db *MemoryDB) *AuthMemory {
+	return &AuthMemory{db: db}
+}
+
+func (r *AuthMemory) CreateUser(ctx context.Context, user todolist_app.User) (int, error) {
+	var id int
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		if err := checkLength("user", 255, user.Name, user.Username, user.Password); err != nil {
+			return err
+		}
+
+		for _, u := range tx.users {
+			if u.Username == user.Username {
+				return todolist_app.NewConflictError("user already exists")
+			}
+		}
+
+		id = tx.nextId()
+		user.Id = id
+		tx.users[id] = user
+
+		return nil
+	})
+
+	return id, err
+}
+
+func (r *AuthMemory) GetUser(ctx context.Context, username string) (todolist_app.User, error) {
+	var user todolist_app.User
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, u := range s.users {
+			if u.Username == username {
+				user = u
+				return nil
+			}
+		}
+
+		return notFound("user")
+	})
+
+	return user, err
+}
+
+func (r *AuthMemory) UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		if user, ok := tx.users[userId]; ok {
+			user.Password = passwordHash
+			tx.users[userId] = user
+		}
+
+		return nil
+	})
+}
+
+func (r *AuthMemory) CreateRefreshToken(ctx context.Context, token todolist_app.RefreshToken) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		return tx.createRefreshToken(token)
+	})
+}
+
+func (s *memoryState) createRefreshToken(token todolist_app.RefreshToken) error {
+	if _, ok := s.users[token.UserId]; !ok {
+		return notFound("user")
+	}
+
+	for _, t := range s.refreshTokens {
+		if t.TokenHash == token.TokenHash {
+			return todolist_app.NewConflictError("refresh token already exists")
+		}
+	}
+
+	token.Id = s.nextId()
+	token.UsedAt = nil
+	token.RevokedAt = nil
+	s.refreshTokens[token.Id] = token
+
+	return nil
+}
+
+func (r *AuthMemory) GetRefreshToken(ctx context.Context, tokenHash string) (todolist_app.RefreshToken, error) {
+	var token todolist_app.RefreshToken
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, t := range s.refreshTokens {
+			if t.TokenHash == tokenHash {
+				token = t
+				return nil
+			}
+		}
+
+		return notFound("refresh token")
+	})
+
+	return token, err
+}
+
+// RotateRefreshToken marks the token as used and stores its successor. It returns sql.ErrNoRows
+// if the token has already been used or revoked, e.g. by a concurrent refresh.
+func (r *AuthMemory) RotateRefreshToken(ctx context.Context, usedId int, next todolist_app.RefreshToken) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		used, ok := tx.refreshTokens[usedId]
+		if !ok || used.UsedAt != nil || used.RevokedAt != nil {
+			return sql.ErrNoRows
+		}
+
+		now := tx.now
+		used.UsedAt = &now
+		tx.refreshTokens[usedId] = used
+
+		return tx.createRefreshToken(next)
+	})
+}
+
+func (r *AuthMemory) RevokeSession(ctx context.Context, sessionId string) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		now := tx.now
+		for id, t := range tx.refreshTokens {
+			if t.SessionId == sessionId && t.RevokedAt == nil {
+				t.RevokedAt = &now
+				tx.refreshTokens[id] = t
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *AuthMemory) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	var active bool
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, t := range s.refreshTokens {
+			if t.SessionId == sessionId && t.RevokedAt == nil {
+				active = true
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return active, err
+}
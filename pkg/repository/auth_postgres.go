@@ -1,35 +1,142 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"time"
 	todolist_app "todolist-app"
 )
 
 type AuthPostgres struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration
 }
 
-func NewAuthPostgres(db *sqlx.DB) *AuthPostgres {
-	return &AuthPostgres{db: db}
+func NewAuthPostgres(db *sqlx.DB, timeout time.Duration) *AuthPostgres {
+	return &AuthPostgres{db: db, timeout: timeout}
 }
 
-func (r *AuthPostgres) CreateUser(user todolist_app.User) (int, error) {
+func (r *AuthPostgres) CreateUser(ctx context.Context, user todolist_app.User) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	var id int
 	query := fmt.Sprintf("INSERT INTO %s (name, username, password_hash) values ($1, $2, $3) RETURNING id", usersTable)
 
-	row := r.db.QueryRow(query, user.Name, user.Username, user.Password)
+	row := r.db.QueryRowContext(ctx, query, user.Name, user.Username, user.Password)
 	if err := row.Scan(&id); err != nil {
-		return 0, err
+		return 0, translateError(err, "user")
 	}
 
 	return id, nil
 }
 
-func (r *AuthPostgres) GetUser(username, password string) (todolist_app.User, error) {
+func (r *AuthPostgres) GetUser(ctx context.Context, username string) (todolist_app.User, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	var user todolist_app.User
-	query := fmt.Sprintf("SELECT id FROM %s WHERE username=$1 AND password_hash=$2", usersTable)
-	err := r.db.Get(&user, query, username, password)
+	query := fmt.Sprintf("SELECT id, name, username, password_hash FROM %s WHERE username=$1", usersTable)
+	err := r.db.GetContext(ctx, &user, query, username)
+
+	return user, translateError(err, "user")
+}
+
+func (r *AuthPostgres) UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("UPDATE %s SET password_hash=$1 WHERE id=$2", usersTable)
+	_, err := r.db.ExecContext(ctx, query, passwordHash, userId)
+
+	return err
+}
+
+func (r *AuthPostgres) CreateRefreshToken(ctx context.Context, token todolist_app.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("INSERT INTO %s (user_id, session_id, token_hash, expires_at) values ($1, $2, $3, $4)",
+		refreshTokensTable)
+	_, err := r.db.ExecContext(ctx, query, token.UserId, token.SessionId, token.TokenHash, token.ExpiresAt)
+
+	return err
+}
+
+func (r *AuthPostgres) GetRefreshToken(ctx context.Context, tokenHash string) (todolist_app.RefreshToken, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var token todolist_app.RefreshToken
+	query := fmt.Sprintf(`SELECT id, user_id, session_id, token_hash, expires_at, used_at, revoked_at FROM %s
+								WHERE token_hash = $1`, refreshTokensTable)
+	err := r.db.GetContext(ctx, &token, query, tokenHash)
+
+	return token, translateError(err, "refresh token")
+}
+
+// RotateRefreshToken marks the token as used and stores its successor. It returns sql.ErrNoRows
+// if the token has already been used or revoked, e.g. by a concurrent refresh.
+func (r *AuthPostgres) RotateRefreshToken(ctx context.Context, usedId int, next todolist_app.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	useQuery := fmt.Sprintf("UPDATE %s SET used_at = now() WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL",
+		refreshTokensTable)
+	res, err := tx.ExecContext(ctx, useQuery, usedId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	used, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if used == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+
+	createQuery := fmt.Sprintf("INSERT INTO %s (user_id, session_id, token_hash, expires_at) values ($1, $2, $3, $4)",
+		refreshTokensTable)
+	_, err = tx.ExecContext(ctx, createQuery, next.UserId, next.SessionId, next.TokenHash, next.ExpiresAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *AuthPostgres) RevokeSession(ctx context.Context, sessionId string) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("UPDATE %s SET revoked_at = now() WHERE session_id = $1 AND revoked_at IS NULL",
+		refreshTokensTable)
+	_, err := r.db.ExecContext(ctx, query, sessionId)
+
+	return err
+}
+
+func (r *AuthPostgres) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var active bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE session_id = $1 AND revoked_at IS NULL)",
+		refreshTokensTable)
+	err := r.db.GetContext(ctx, &active, query, sessionId)
 
-	return user, err
+	return active, err
 }
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type AuthSQLite struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewAuthSQLite(db *sqlx.DB, timeout time.Duration) *AuthSQLite {
+	return &AuthSQLite{db: db, timeout: timeout}
+}
+
+func (r *AuthSQLite) CreateUser(ctx context.Context, user todolist_app.User) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var id int
+	query := fmt.Sprintf("INSERT INTO %s (name, username, password_hash) values (?, ?, ?) RETURNING id", usersTable)
+
+	row := r.db.QueryRowContext(ctx, query, user.Name, user.Username, user.Password)
+	if err := row.Scan(&id); err != nil {
+		return 0, translateError(err, "user")
+	}
+
+	return id, nil
+}
+
+func (r *AuthSQLite) GetUser(ctx context.Context, username string) (todolist_app.User, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var user todolist_app.User
+	query := fmt.Sprintf("SELECT id, name, username, password_hash FROM %s WHERE username=?", usersTable)
+	err := r.db.GetContext(ctx, &user, query, username)
+
+	return user, translateError(err, "user")
+}
+
+func (r *AuthSQLite) UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("UPDATE %s SET password_hash=? WHERE id=?", usersTable)
+	_, err := r.db.ExecContext(ctx, query, passwordHash, userId)
+
+	return err
+}
+
+func (r *AuthSQLite) CreateRefreshToken(ctx context.Context, token todolist_app.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("INSERT INTO %s (user_id, session_id, token_hash, expires_at, created_at) values (?, ?, ?, ?, ?)",
+		refreshTokensTable)
+	_, err := r.db.ExecContext(ctx, query, token.UserId, token.SessionId, token.TokenHash, sqliteTime(&token.ExpiresAt), sqliteNow())
+
+	return err
+}
+
+func (r *AuthSQLite) GetRefreshToken(ctx context.Context, tokenHash string) (todolist_app.RefreshToken, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var token todolist_app.RefreshToken
+	query := fmt.Sprintf(`SELECT id, user_id, session_id, token_hash, expires_at, used_at, revoked_at FROM %s
+								WHERE token_hash = ?`, refreshTokensTable)
+	err := r.db.GetContext(ctx, &token, query, tokenHash)
+
+	return token, translateError(err, "refresh token")
+}
+
+// RotateRefreshToken marks the token as used and stores its successor. It returns sql.ErrNoRows
+// if the token has already been used or revoked, e.g. by a concurrent refresh.
+func (r *AuthSQLite) RotateRefreshToken(ctx context.Context, usedId int, next todolist_app.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := sqliteNow()
+	useQuery := fmt.Sprintf("UPDATE %s SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL",
+		refreshTokensTable)
+	res, err := tx.ExecContext(ctx, useQuery, now, usedId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	used, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if used == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+
+	createQuery := fmt.Sprintf("INSERT INTO %s (user_id, session_id, token_hash, expires_at, created_at) values (?, ?, ?, ?, ?)",
+		refreshTokensTable)
+	_, err = tx.ExecContext(ctx, createQuery, next.UserId, next.SessionId, next.TokenHash, sqliteTime(&next.ExpiresAt), now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *AuthSQLite) RevokeSession(ctx context.Context, sessionId string) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("UPDATE %s SET revoked_at = ? WHERE session_id = ? AND revoked_at IS NULL",
+		refreshTokensTable)
+	_, err := r.db.ExecContext(ctx, query, sqliteNow(), sessionId)
+
+	return err
+}
+
+func (r *AuthSQLite) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var active bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE session_id = ? AND revoked_at IS NULL)",
+		refreshTokensTable)
+	err := r.db.GetContext(ctx, &active, query, sessionId)
+
+	return active, err
+}
@@ -0,0 +1,1136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	todolist_app "todolist-app"
+)
+
+// testConformance checks the behaviour every backend of the repositories has to share. The tests only
+// create records of their own users, so they can run against a database that holds other data, except
+// for Purge, which empties the trash of every user.
+func testConformance(t *testing.T, repos *Repository) {
+	t.Run("Authorization", func(t *testing.T) { testAuthorization(t, repos) })
+	t.Run("TodoList", func(t *testing.T) { testTodoList(t, repos) })
+	t.Run("TodoItem", func(t *testing.T) { testTodoItem(t, repos) })
+	t.Run("Label", func(t *testing.T) { testLabel(t, repos) })
+	t.Run("Search", func(t *testing.T) { testSearch(t, repos) })
+	t.Run("Activity", func(t *testing.T) { testActivity(t, repos) })
+	t.Run("Trash", func(t *testing.T) { testTrash(t, repos) })
+}
+
+var userSeq int64
+
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano(), atomic.AddInt64(&userSeq, 1))
+}
+
+func createUser(t *testing.T, repos *Repository) (int, string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	username := uniqueName("user")
+	id, err := repos.CreateUser(ctx, todolist_app.User{Name: "Test User", Username: username, Password: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return id, username
+}
+
+func createList(t *testing.T, repos *Repository, userId int, title string) int {
+	t.Helper()
+
+	ctx := context.Background()
+
+	id, err := repos.TodoList.Create(ctx, userId, todolist_app.TodoList{Title: title, Description: "description"})
+	if err != nil {
+		t.Fatalf("TodoList.Create: %v", err)
+	}
+
+	return id
+}
+
+func createItem(t *testing.T, repos *Repository, userId, listId int, item todolist_app.TodoItem) int {
+	t.Helper()
+
+	ctx := context.Background()
+
+	id, err := repos.TodoItem.Create(ctx, userId, listId, item)
+	if err != nil {
+		t.Fatalf("TodoItem.Create: %v", err)
+	}
+
+	return id
+}
+
+func mustOk(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func wantCode(t *testing.T, err error, code todolist_app.ErrorCode) {
+	t.Helper()
+
+	if got := todolist_app.ErrorCodeOf(err); got != code {
+		t.Fatalf("got error %v with code %q, want code %q", err, got, code)
+	}
+}
+
+func itemIds(items []todolist_app.TodoItem) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+
+	return ids
+}
+
+func wantIds(t *testing.T, got, want []int) {
+	t.Helper()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got ids %v, want %v", got, want)
+	}
+}
+
+func testAuthorization(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	t.Run("users", func(t *testing.T) {
+		id, username := createUser(t, repos)
+
+		user, err := repos.GetUser(ctx, username)
+		mustOk(t, err)
+		if user.Id != id || user.Username != username || user.Password != "hash" {
+			t.Fatalf("GetUser returned %+v", user)
+		}
+
+		_, err = repos.CreateUser(ctx, todolist_app.User{Name: "Other", Username: username, Password: "hash"})
+		wantCode(t, err, todolist_app.CodeConflict)
+
+		_, err = repos.GetUser(ctx, uniqueName("missing"))
+		wantCode(t, err, todolist_app.CodeNotFound)
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser of a missing user returned %v, want it to wrap sql.ErrNoRows", err)
+		}
+
+		mustOk(t, repos.UpdatePasswordHash(ctx, id, "new-hash"))
+		user, err = repos.GetUser(ctx, username)
+		mustOk(t, err)
+		if user.Password != "new-hash" {
+			t.Fatalf("password hash is %q after the update", user.Password)
+		}
+	})
+
+	t.Run("refresh tokens", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		session := uniqueName("session")
+		expires := time.Now().Add(time.Hour)
+
+		first := todolist_app.RefreshToken{UserId: userId, SessionId: session, TokenHash: uniqueName("hash"), ExpiresAt: expires}
+		mustOk(t, repos.CreateRefreshToken(ctx, first))
+
+		stored, err := repos.GetRefreshToken(ctx, first.TokenHash)
+		mustOk(t, err)
+		if stored.UserId != userId || stored.SessionId != session || stored.UsedAt != nil || stored.RevokedAt != nil {
+			t.Fatalf("GetRefreshToken returned %+v", stored)
+		}
+
+		active, err := repos.IsSessionActive(ctx, session)
+		mustOk(t, err)
+		if !active {
+			t.Fatal("session is not active after creating a token")
+		}
+
+		second := todolist_app.RefreshToken{UserId: userId, SessionId: session, TokenHash: uniqueName("hash"), ExpiresAt: expires}
+		mustOk(t, repos.RotateRefreshToken(ctx, stored.Id, second))
+
+		used, err := repos.GetRefreshToken(ctx, first.TokenHash)
+		mustOk(t, err)
+		if used.UsedAt == nil {
+			t.Fatal("rotated token is not marked as used")
+		}
+
+		third := todolist_app.RefreshToken{UserId: userId, SessionId: session, TokenHash: uniqueName("hash"), ExpiresAt: expires}
+		if err := repos.RotateRefreshToken(ctx, stored.Id, third); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("rotating a used token returned %v, want sql.ErrNoRows", err)
+		}
+
+		_, err = repos.GetRefreshToken(ctx, third.TokenHash)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		mustOk(t, repos.RevokeSession(ctx, session))
+		active, err = repos.IsSessionActive(ctx, session)
+		mustOk(t, err)
+		if active {
+			t.Fatal("session is active after revoking it")
+		}
+	})
+}
+
+func testTodoList(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	t.Run("ownership", func(t *testing.T) {
+		owner, _ := createUser(t, repos)
+		stranger, _ := createUser(t, repos)
+		listId := createList(t, repos, owner, "Groceries")
+
+		list, err := repos.TodoList.GetById(ctx, owner, listId)
+		mustOk(t, err)
+		if list.Title != "Groceries" || list.Role != todolist_app.RoleOwner || list.Version != 1 {
+			t.Fatalf("GetById returned %+v", list)
+		}
+
+		_, err = repos.TodoList.GetById(ctx, stranger, listId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		_, err = repos.TodoList.GetRole(ctx, stranger, listId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		wantCode(t, repos.TodoList.Delete(ctx, stranger, listId), todolist_app.CodeNotFound)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "Groceries")
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := repos.TodoList.GetById(canceled, userId, listId); !errors.Is(err, context.Canceled) {
+			t.Fatalf("GetById with a canceled context returned %v", err)
+		}
+
+		if err := repos.TodoList.Delete(canceled, userId, listId); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Delete with a canceled context returned %v", err)
+		}
+
+		_, err := repos.TodoList.GetById(ctx, userId, listId)
+		mustOk(t, err)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		for _, title := range []string{"c list", "a list", "b list", "other"} {
+			createList(t, repos, userId, title)
+		}
+
+		filter := todolist_app.ListFilter{Page: todolist_app.Page{Limit: 2, Sort: "title"}, Q: "LIST"}
+		lists, next, err := repos.TodoList.GetAll(ctx, userId, filter)
+		mustOk(t, err)
+		if len(lists) != 2 || lists[0].Title != "a list" || lists[1].Title != "b list" || next == "" {
+			t.Fatalf("first page is %+v with cursor %q", lists, next)
+		}
+
+		filter.Cursor = next
+		lists, next, err = repos.TodoList.GetAll(ctx, userId, filter)
+		mustOk(t, err)
+		if len(lists) != 1 || lists[0].Title != "c list" || next != "" {
+			t.Fatalf("second page is %+v with cursor %q", lists, next)
+		}
+
+		filter.Sort = "-title"
+		_, _, err = repos.TodoList.GetAll(ctx, userId, filter)
+		wantCode(t, err, todolist_app.CodeValidation)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "Work")
+
+		title := "Work stuff"
+		mustOk(t, repos.TodoList.Update(ctx, userId, listId, todolist_app.UpdateListInput{Title: &title}))
+
+		list, err := repos.TodoList.GetById(ctx, userId, listId)
+		mustOk(t, err)
+		if list.Title != title || list.Description != "description" || list.Version != 2 {
+			t.Fatalf("GetById after the update returned %+v", list)
+		}
+
+		stale := 1
+		err = repos.TodoList.Update(ctx, userId, listId, todolist_app.UpdateListInput{Title: &title, Version: &stale})
+		wantCode(t, err, todolist_app.CodePreconditionFailed)
+
+		long := strings.Repeat("x", 256)
+		err = repos.TodoList.Update(ctx, userId, listId, todolist_app.UpdateListInput{Title: &long})
+		wantCode(t, err, todolist_app.CodeValidation)
+	})
+
+	t.Run("members", func(t *testing.T) {
+		owner, ownerName := createUser(t, repos)
+		member, memberName := createUser(t, repos)
+		listId := createList(t, repos, owner, "Shared")
+
+		userId, err := repos.SaveMember(ctx, owner, listId, memberName, todolist_app.RoleViewer)
+		mustOk(t, err)
+		if userId != member {
+			t.Fatalf("SaveMember returned user %d, want %d", userId, member)
+		}
+
+		_, err = repos.SaveMember(ctx, owner, listId, memberName, todolist_app.RoleEditor)
+		mustOk(t, err)
+
+		role, err := repos.TodoList.GetRole(ctx, member, listId)
+		mustOk(t, err)
+		if role != todolist_app.RoleEditor {
+			t.Fatalf("member has role %q, want editor", role)
+		}
+
+		members, err := repos.GetMembers(ctx, listId)
+		mustOk(t, err)
+		if len(members) != 2 || members[0].UserId != owner || members[1].UserId != member {
+			t.Fatalf("GetMembers returned %+v", members)
+		}
+
+		_, err = repos.SaveMember(ctx, owner, listId, uniqueName("missing"), todolist_app.RoleViewer)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		mustOk(t, repos.DeleteMember(ctx, owner, listId, member))
+		wantCode(t, repos.DeleteMember(ctx, owner, listId, member), todolist_app.CodeNotFound)
+
+		_, err = repos.TodoList.GetById(ctx, member, listId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		// a list keeps at least one owner
+		_, err = repos.SaveMember(ctx, owner, listId, ownerName, todolist_app.RoleEditor)
+		wantCode(t, err, todolist_app.CodeConflict)
+		wantCode(t, repos.DeleteMember(ctx, owner, listId, owner), todolist_app.CodeConflict)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "Old")
+		itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Item"})
+
+		mustOk(t, repos.TodoList.Delete(ctx, userId, listId))
+
+		_, err := repos.TodoList.GetById(ctx, userId, listId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		_, err = repos.TodoItem.GetById(ctx, userId, itemId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		wantCode(t, repos.TodoList.Delete(ctx, userId, listId), todolist_app.CodeNotFound)
+	})
+}
+
+func testTodoItem(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	t.Run("create and read", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		stranger, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+
+		first := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "First", Priority: todolist_app.PriorityHigh})
+		second := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Second"})
+
+		item, err := repos.TodoItem.GetById(ctx, userId, first)
+		mustOk(t, err)
+		if item.Title != "First" || item.ListId != listId || item.Done || item.Version != 1 ||
+			item.Priority != todolist_app.PriorityHigh || item.CompletedAt != nil {
+			t.Fatalf("GetById returned %+v", item)
+		}
+
+		items, next, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{first, second})
+		if next != "" {
+			t.Fatalf("a single page has the cursor %q", next)
+		}
+
+		items, _, err = repos.TodoItem.GetAll(ctx, stranger, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{})
+
+		_, err = repos.TodoItem.GetById(ctx, stranger, first)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		_, err = repos.TodoItem.GetRole(ctx, stranger, first)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		_, err = repos.TodoItem.Create(ctx, userId, listId, todolist_app.TodoItem{Title: strings.Repeat("x", 256)})
+		wantCode(t, err, todolist_app.CodeValidation)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Item"})
+
+		done := true
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{Done: &done}))
+
+		item, err := repos.TodoItem.GetById(ctx, userId, itemId)
+		mustOk(t, err)
+		if !item.Done || item.CompletedAt == nil || item.Version != 2 {
+			t.Fatalf("completed item is %+v", item)
+		}
+
+		stale := 1
+		err = repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{Done: &done, Version: &stale})
+		wantCode(t, err, todolist_app.CodePreconditionFailed)
+
+		undone := false
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{Done: &undone}))
+
+		item, err = repos.TodoItem.GetById(ctx, userId, itemId)
+		mustOk(t, err)
+		if item.Done || item.CompletedAt != nil {
+			t.Fatalf("reopened item is %+v", item)
+		}
+
+		filter := todolist_app.ItemFilter{Done: &done}
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, filter)
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{})
+	})
+
+	t.Run("subtasks", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		parentId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Parent"})
+		doneId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Done", ParentId: &parentId})
+		openId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Open", ParentId: &parentId})
+
+		done := true
+		mustOk(t, repos.TodoItem.Update(ctx, userId, doneId, todolist_app.UpdateItemInput{Done: &done}))
+
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{parentId})
+		if items[0].Progress != (todolist_app.Progress{Done: 1, Total: 2}) {
+			t.Fatalf("parent progress is %+v", items[0].Progress)
+		}
+
+		subtasks, err := repos.GetSubtasks(ctx, userId, parentId)
+		mustOk(t, err)
+		wantIds(t, itemIds(subtasks), []int{doneId, openId})
+
+		mustOk(t, repos.TodoItem.Delete(ctx, userId, parentId))
+
+		_, err = repos.TodoItem.GetById(ctx, userId, openId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+		wantCode(t, repos.TodoItem.Delete(ctx, userId, parentId), todolist_app.CodeNotFound)
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		a := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "a"})
+		b := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "b"})
+		c := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "c"})
+
+		mustOk(t, repos.Reorder(ctx, userId, listId, []int{c, a}))
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{c, a, b})
+
+		mustOk(t, repos.SetPosition(ctx, userId, b, todolist_app.ItemPositionInput{BeforeId: &c}))
+		items, _, err = repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{b, c, a})
+
+		wantCode(t, repos.Reorder(ctx, userId, listId, []int{a, a}), todolist_app.CodeValidation)
+	})
+
+	t.Run("move", func(t *testing.T) {
+		owner, _ := createUser(t, repos)
+		viewer, viewerName := createUser(t, repos)
+		source := createList(t, repos, owner, "Source")
+		target := createList(t, repos, owner, "Target")
+		parentId := createItem(t, repos, owner, source, todolist_app.TodoItem{Title: "Parent"})
+		subtaskId := createItem(t, repos, owner, source, todolist_app.TodoItem{Title: "Subtask", ParentId: &parentId})
+
+		_, err := repos.SaveMember(ctx, owner, source, viewerName, todolist_app.RoleViewer)
+		mustOk(t, err)
+		viewerList := createList(t, repos, viewer, "Viewer's")
+		wantCode(t, repos.Move(ctx, viewer, parentId, viewerList), todolist_app.CodeForbidden)
+
+		wantCode(t, repos.Move(ctx, owner, subtaskId, target), todolist_app.CodeValidation)
+
+		before, err := repos.TodoItem.GetById(ctx, owner, parentId)
+		mustOk(t, err)
+
+		mustOk(t, repos.Move(ctx, owner, parentId, target))
+		for _, id := range []int{parentId, subtaskId} {
+			item, err := repos.TodoItem.GetById(ctx, owner, id)
+			mustOk(t, err)
+			if item.ListId != target {
+				t.Fatalf("item %d is in list %d after the move, want %d", id, item.ListId, target)
+			}
+		}
+
+		// a client that read the item before the move must not overwrite it unnoticed
+		title := "Stale"
+		err = repos.TodoItem.Update(ctx, owner, parentId, todolist_app.UpdateItemInput{Title: &title, Version: &before.Version})
+		wantCode(t, err, todolist_app.CodePreconditionFailed)
+	})
+
+	t.Run("series", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		due := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+		rule := "FREQ=DAILY"
+
+		firstId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Daily", DueAt: &due, Recurrence: &rule})
+		first, err := repos.TodoItem.GetById(ctx, userId, firstId)
+		mustOk(t, err)
+		if first.SeriesId == nil || *first.SeriesId != firstId {
+			t.Fatalf("the first item of a series has series id %v, want %d", first.SeriesId, firstId)
+		}
+
+		_, err = repos.TodoItem.Create(ctx, userId, listId, todolist_app.TodoItem{
+			Title: "Daily", DueAt: &due, Recurrence: &rule, SeriesId: first.SeriesId,
+		})
+		wantCode(t, err, todolist_app.CodeConflict)
+
+		nextDue := due.AddDate(0, 0, 1)
+		nextId := createItem(t, repos, userId, listId, todolist_app.TodoItem{
+			Title: "Daily", DueAt: &nextDue, Recurrence: &rule, SeriesId: first.SeriesId,
+		})
+
+		title := "Every day"
+		mustOk(t, repos.UpdateSeries(ctx, userId, firstId, todolist_app.UpdateSeriesInput{Title: &title}))
+		next, err := repos.TodoItem.GetById(ctx, userId, nextId)
+		mustOk(t, err)
+		if next.Title != title {
+			t.Fatalf("series item has the title %q after the series update", next.Title)
+		}
+
+		mustOk(t, repos.StopSeries(ctx, userId, nextId))
+		next, err = repos.TodoItem.GetById(ctx, userId, nextId)
+		mustOk(t, err)
+		if next.Recurrence != nil {
+			t.Fatalf("series item repeats with %q after stopping the series", *next.Recurrence)
+		}
+
+		wantCode(t, repos.StopSeries(ctx, userId, firstId), todolist_app.CodeNotFound)
+	})
+
+	t.Run("completing a recurring item", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		due := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+		rule := "FREQ=DAILY"
+		itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Daily", DueAt: &due, Recurrence: &rule})
+
+		done, undone := true, false
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{Done: &done}))
+
+		// completing the item again must not spawn the same occurrence twice
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{Done: &undone}))
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{Done: &done}))
+
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		if len(items) != 2 {
+			t.Fatalf("the list holds %d items after completing a daily item twice, want 2", len(items))
+		}
+
+		next := items[1]
+		if next.Id == itemId {
+			next = items[0]
+		}
+
+		nextDue := due.AddDate(0, 0, 1)
+		switch {
+		case next.Done || next.Title != "Daily":
+			t.Fatalf("next occurrence is %+v", next)
+		case next.DueAt == nil || !next.DueAt.Equal(nextDue):
+			t.Fatalf("next occurrence is due %v, want %v", next.DueAt, nextDue)
+		case next.SeriesId == nil || *next.SeriesId != itemId:
+			t.Fatalf("next occurrence has series id %v, want %d", next.SeriesId, itemId)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		otherList := createList(t, repos, userId, "Other")
+		itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Item"})
+		foreignId := createItem(t, repos, userId, otherList, todolist_app.TodoItem{Title: "Foreign"})
+
+		done := true
+		operations := []todolist_app.BatchOperation{
+			{Op: todolist_app.BatchOpCreate, Item: &todolist_app.TodoItem{Title: "New"}},
+			{Op: todolist_app.BatchOpUpdate, Id: &itemId, Input: &todolist_app.UpdateItemInput{Done: &done}},
+			{Op: todolist_app.BatchOpDelete, Id: &foreignId},
+		}
+
+		_, err := repos.Batch(ctx, userId, listId, operations, true)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{itemId})
+		if items[0].Done {
+			t.Fatal("a failed atomic batch kept its update")
+		}
+
+		results, err := repos.Batch(ctx, userId, listId, operations, false)
+		mustOk(t, err)
+		if len(results) != 3 || results[0].Error != nil || results[1].Error != nil || results[2].Error == nil ||
+			results[2].Error.Code != todolist_app.CodeNotFound {
+			t.Fatalf("partial batch returned %+v", results)
+		}
+
+		items, _, err = repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{itemId, results[0].Id})
+		if !items[0].Done {
+			t.Fatal("a partial batch did not keep its update")
+		}
+
+		_, err = repos.TodoItem.GetById(ctx, userId, foreignId)
+		mustOk(t, err)
+	})
+
+	t.Run("list shortcuts", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		a := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "a"})
+		b := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "b"})
+
+		done := true
+		mustOk(t, repos.TodoItem.Update(ctx, userId, a, todolist_app.UpdateItemInput{Done: &done}))
+
+		changed, err := repos.SetDone(ctx, userId, listId, true)
+		mustOk(t, err)
+		wantIds(t, itemIds(changed), []int{b})
+
+		changed, err = repos.SetDone(ctx, userId, listId, false)
+		mustOk(t, err)
+		wantIds(t, itemIds(changed), []int{a, b})
+
+		mustOk(t, repos.TodoItem.Update(ctx, userId, b, todolist_app.UpdateItemInput{Done: &done}))
+		cleared, err := repos.DeleteDone(ctx, userId, listId)
+		mustOk(t, err)
+		if cleared != 1 {
+			t.Fatalf("DeleteDone removed %d items, want 1", cleared)
+		}
+
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{a})
+	})
+
+	t.Run("filters and sorting", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		past := time.Now().Add(-time.Hour)
+		low := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "low", Priority: todolist_app.PriorityLow})
+		urgent := createItem(t, repos, userId, listId, todolist_app.TodoItem{
+			Title: "urgent", Priority: todolist_app.PriorityUrgent, DueAt: &past,
+		})
+		medium := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "medium", Priority: todolist_app.PriorityMedium})
+
+		items, _, err := repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{Page: todolist_app.Page{Sort: "-priority"}})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{urgent, medium, low})
+
+		items, _, err = repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{Overdue: true})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{urgent})
+
+		items, _, err = repos.TodoItem.GetAll(ctx, userId, listId, todolist_app.ItemFilter{Q: "MED"})
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{medium})
+
+		page := todolist_app.ItemFilter{Page: todolist_app.Page{Limit: 2, Sort: "due_at"}}
+		items, next, err := repos.GetAllByUser(ctx, userId, page)
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{urgent, low})
+
+		page.Cursor = next
+		items, next, err = repos.GetAllByUser(ctx, userId, page)
+		mustOk(t, err)
+		wantIds(t, itemIds(items), []int{medium})
+		if next != "" {
+			t.Fatalf("the last page has the cursor %q", next)
+		}
+	})
+
+	t.Run("schedule", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		due := time.Date(2030, 1, 10, 9, 0, 0, 0, time.UTC)
+		remind := due.Add(-time.Hour)
+		itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Item", DueAt: &due, RemindAt: &remind})
+
+		// the reminder has to stay before the stored due time and the other way around
+		late := due.Add(time.Hour)
+		err := repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{RemindAt: &late})
+		wantCode(t, err, todolist_app.CodeValidation)
+
+		early := remind.Add(-time.Hour)
+		err = repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{DueAt: &early})
+		wantCode(t, err, todolist_app.CodeValidation)
+
+		item, err := repos.TodoItem.GetById(ctx, userId, itemId)
+		mustOk(t, err)
+		if item.Version != 1 || !item.DueAt.Equal(due) || !item.RemindAt.Equal(remind) {
+			t.Fatalf("a refused update changed the item to %+v", item)
+		}
+
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{DueAt: &late}))
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{ClearDueAt: true}))
+
+		item, err = repos.TodoItem.GetById(ctx, userId, itemId)
+		mustOk(t, err)
+		if item.DueAt != nil || item.RemindAt == nil || !item.RemindAt.Equal(remind) {
+			t.Fatalf("item is due %v with a reminder at %v after clearing the due time", item.DueAt, item.RemindAt)
+		}
+
+		// without a due time any reminder goes
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{RemindAt: &late}))
+		mustOk(t, repos.TodoItem.Update(ctx, userId, itemId, todolist_app.UpdateItemInput{ClearRemindAt: true}))
+
+		item, err = repos.TodoItem.GetById(ctx, userId, itemId)
+		mustOk(t, err)
+		if item.DueAt != nil || item.RemindAt != nil {
+			t.Fatalf("item is due %v with a reminder at %v after clearing both", item.DueAt, item.RemindAt)
+		}
+
+		activity, _, err := repos.Activity.GetByItem(ctx, userId, itemId, todolist_app.Page{})
+		mustOk(t, err)
+		change, ok := activity[0].Changes["remind_at"]
+		if !ok || change.Before == nil {
+			t.Fatalf("clearing the reminder recorded %+v", activity[0].Changes)
+		}
+	})
+}
+
+func labelIds(labels []todolist_app.Label) []int {
+	ids := make([]int, len(labels))
+	for i, label := range labels {
+		ids[i] = label.Id
+	}
+
+	return ids
+}
+
+func testLabel(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	t.Run("crud", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+
+		work, err := repos.Label.Create(ctx, userId, todolist_app.Label{Name: "work", Color: "#ff0000"})
+		mustOk(t, err)
+		home, err := repos.Label.Create(ctx, userId, todolist_app.Label{Name: "home"})
+		mustOk(t, err)
+
+		_, err = repos.Label.Create(ctx, userId, todolist_app.Label{Name: "work"})
+		wantCode(t, err, todolist_app.CodeConflict)
+
+		labels, err := repos.Label.GetAll(ctx, userId)
+		mustOk(t, err)
+		wantIds(t, labelIds(labels), []int{home, work})
+
+		label, err := repos.Label.GetById(ctx, userId, work)
+		mustOk(t, err)
+		if label.Name != "work" || label.Color != "#ff0000" {
+			t.Fatalf("GetById returned %+v", label)
+		}
+
+		name := "office"
+		mustOk(t, repos.Label.Update(ctx, userId, work, todolist_app.UpdateLabelInput{Name: &name}))
+		label, err = repos.Label.GetById(ctx, userId, work)
+		mustOk(t, err)
+		if label.Name != "office" || label.Color != "#ff0000" {
+			t.Fatalf("label is %+v after renaming it", label)
+		}
+
+		taken := "home"
+		err = repos.Label.Update(ctx, userId, work, todolist_app.UpdateLabelInput{Name: &taken})
+		wantCode(t, err, todolist_app.CodeConflict)
+
+		mustOk(t, repos.Label.Delete(ctx, userId, home))
+		_, err = repos.Label.GetById(ctx, userId, home)
+		wantCode(t, err, todolist_app.CodeNotFound)
+		wantCode(t, repos.Label.Delete(ctx, userId, home), todolist_app.CodeNotFound)
+	})
+
+	t.Run("ownership", func(t *testing.T) {
+		owner, _ := createUser(t, repos)
+		member, memberName := createUser(t, repos)
+		listId := createList(t, repos, owner, "Shared")
+		itemId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Item"})
+		_, err := repos.SaveMember(ctx, owner, listId, memberName, todolist_app.RoleEditor)
+		mustOk(t, err)
+
+		labelId, err := repos.Label.Create(ctx, owner, todolist_app.Label{Name: "urgent"})
+		mustOk(t, err)
+
+		// label names are unique per user only
+		memberLabel, err := repos.Label.Create(ctx, member, todolist_app.Label{Name: "urgent"})
+		mustOk(t, err)
+
+		_, err = repos.Label.GetById(ctx, member, labelId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		name := "mine"
+		wantCode(t, repos.Label.Update(ctx, member, labelId, todolist_app.UpdateLabelInput{Name: &name}), todolist_app.CodeNotFound)
+		wantCode(t, repos.Label.Delete(ctx, member, labelId), todolist_app.CodeNotFound)
+
+		before, err := repos.TodoItem.GetById(ctx, owner, itemId)
+		mustOk(t, err)
+
+		mustOk(t, repos.Attach(ctx, itemId, labelId))
+		mustOk(t, repos.Attach(ctx, itemId, labelId))
+		mustOk(t, repos.Attach(ctx, itemId, memberLabel))
+
+		// labels are private, so they leave the version of the shared item alone
+		after, err := repos.TodoItem.GetById(ctx, owner, itemId)
+		mustOk(t, err)
+		if after.Version != before.Version {
+			t.Fatalf("attaching labels changed the item version from %d to %d", before.Version, after.Version)
+		}
+
+		labels, err := repos.Label.GetByItem(ctx, owner, itemId)
+		mustOk(t, err)
+		wantIds(t, labelIds(labels), []int{labelId})
+
+		labels, err = repos.Label.GetByItem(ctx, member, itemId)
+		mustOk(t, err)
+		wantIds(t, labelIds(labels), []int{memberLabel})
+
+		wantCode(t, repos.Detach(ctx, member, itemId, labelId), todolist_app.CodeNotFound)
+		wantCode(t, repos.Attach(ctx, itemId, -1), todolist_app.CodeNotFound)
+
+		mustOk(t, repos.Detach(ctx, owner, itemId, labelId))
+		wantCode(t, repos.Detach(ctx, owner, itemId, labelId), todolist_app.CodeNotFound)
+
+		// deleting a label detaches it from its items
+		mustOk(t, repos.Label.Delete(ctx, member, memberLabel))
+		labels, err = repos.Label.GetByItem(ctx, member, itemId)
+		mustOk(t, err)
+		wantIds(t, labelIds(labels), []int{})
+	})
+}
+
+func searchHitKeys(hits []todolist_app.SearchHit) []string {
+	keys := make([]string, len(hits))
+	for i, hit := range hits {
+		keys[i] = fmt.Sprintf("%s:%d", hit.Kind, hit.Id)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func testSearch(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	userId, _ := createUser(t, repos)
+	stranger, _ := createUser(t, repos)
+
+	listId, err := repos.TodoList.Create(ctx, userId, todolist_app.TodoList{Title: "Zebra crossing", Description: "Paint the stripes"})
+	mustOk(t, err)
+	itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Feed the <zebra>", Description: "Hay"})
+	createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Walk the dog"})
+	deletedId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Zebra gone"})
+	mustOk(t, repos.TodoItem.Delete(ctx, userId, deletedId))
+	createList(t, repos, stranger, "Zebra of someone else")
+
+	want := func(hits []todolist_app.SearchHit, keys ...string) {
+		t.Helper()
+
+		sort.Strings(keys)
+		if got := searchHitKeys(hits); fmt.Sprint(got) != fmt.Sprint(keys) {
+			t.Fatalf("search found %v, want %v", got, keys)
+		}
+	}
+	listKey, itemKey := fmt.Sprintf("list:%d", listId), fmt.Sprintf("item:%d", itemId)
+
+	t.Run("matches", func(t *testing.T) {
+		hits, err := repos.Search.Search(ctx, userId, todolist_app.SearchInput{Q: "zebra"})
+		mustOk(t, err)
+		want(hits, listKey, itemKey)
+
+		hits, err = repos.Search.Search(ctx, userId, todolist_app.SearchInput{Q: "ZEBRA stripes"})
+		mustOk(t, err)
+		want(hits, listKey)
+
+		hits, err = repos.Search.Search(ctx, userId, todolist_app.SearchInput{Q: "zebra -crossing"})
+		mustOk(t, err)
+		want(hits, itemKey)
+
+		hits, err = repos.Search.Search(ctx, userId, todolist_app.SearchInput{Q: "giraffe"})
+		mustOk(t, err)
+		want(hits)
+	})
+
+	t.Run("highlight", func(t *testing.T) {
+		hits, err := repos.Search.Search(ctx, userId, todolist_app.SearchInput{Q: "hay"})
+		mustOk(t, err)
+		want(hits, itemKey)
+
+		if hit := hits[0]; hit.ListId != listId || hit.Title != "Feed the &lt;zebra&gt;" || hit.Snippet != "<mark>Hay</mark>" {
+			t.Fatalf("search returned %+v", hit)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		hits, err := repos.Search.Search(ctx, userId, todolist_app.SearchInput{Q: "zebra", Limit: 1})
+		mustOk(t, err)
+		if len(hits) != 1 {
+			t.Fatalf("search with a limit of 1 returned %d hits", len(hits))
+		}
+
+		hits, err = repos.Search.Search(ctx, stranger, todolist_app.SearchInput{Q: "crossing"})
+		mustOk(t, err)
+		want(hits)
+	})
+}
+
+func testActivity(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	t.Run("paging", func(t *testing.T) {
+		userId, username := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		for _, title := range []string{"First", "Second", "Third"} {
+			createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: title})
+		}
+
+		all, next, err := repos.Activity.GetByList(ctx, listId, todolist_app.Page{})
+		mustOk(t, err)
+		if len(all) != 4 || next != "" {
+			t.Fatalf("GetByList returned %d entries and the cursor %q, want 4 entries on one page", len(all), next)
+		}
+
+		for i, a := range all {
+			if i > 0 && a.Id > all[i-1].Id {
+				t.Fatalf("entry %d comes after the older entry %d", a.Id, all[i-1].Id)
+			}
+
+			if a.ListId != listId || a.ActorUsername == nil || *a.ActorUsername != username {
+				t.Fatalf("GetByList returned %+v", a)
+			}
+		}
+
+		if last := all[len(all)-1]; last.Entity != todolist_app.ActivityEntityList || last.Action != todolist_app.ActionCreated {
+			t.Fatalf("the oldest entry is %s %s, want the list being created", last.Entity, last.Action)
+		}
+
+		var paged []int
+		page := todolist_app.Page{Limit: 3}
+		for {
+			activity, next, err := repos.Activity.GetByList(ctx, listId, page)
+			mustOk(t, err)
+			for _, a := range activity {
+				paged = append(paged, a.Id)
+			}
+
+			if next == "" {
+				break
+			}
+			page.Cursor = next
+		}
+
+		want := make([]int, len(all))
+		for i, a := range all {
+			want[i] = a.Id
+		}
+		wantIds(t, paged, want)
+
+		oldest, _, err := repos.Activity.GetByList(ctx, listId, todolist_app.Page{Limit: 1, Sort: "id"})
+		mustOk(t, err)
+		wantIds(t, []int{oldest[0].Id}, want[len(want)-1:])
+
+		_, _, err = repos.Activity.GetByList(ctx, listId, todolist_app.Page{Cursor: "not a cursor"})
+		wantCode(t, err, todolist_app.CodeValidation)
+	})
+
+	t.Run("item history", func(t *testing.T) {
+		owner, _ := createUser(t, repos)
+		member, memberName := createUser(t, repos)
+		stranger, _ := createUser(t, repos)
+		listId := createList(t, repos, owner, "List")
+		itemId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Item"})
+		createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Other"})
+		_, err := repos.SaveMember(ctx, owner, listId, memberName, todolist_app.RoleViewer)
+		mustOk(t, err)
+
+		title := "Renamed"
+		mustOk(t, repos.TodoItem.Update(ctx, owner, itemId, todolist_app.UpdateItemInput{Title: &title}))
+
+		activity, _, err := repos.Activity.GetByItem(ctx, member, itemId, todolist_app.Page{})
+		mustOk(t, err)
+
+		var actions []string
+		for _, a := range activity {
+			if a.ItemId == nil || *a.ItemId != itemId {
+				t.Fatalf("the history of item %d has the entry %+v", itemId, a)
+			}
+			actions = append(actions, a.Action)
+		}
+
+		want := []string{todolist_app.ActionUpdated, todolist_app.ActionCreated}
+		if fmt.Sprint(actions) != fmt.Sprint(want) {
+			t.Fatalf("the history of the item is %v, want %v", actions, want)
+		}
+
+		if changes := activity[0].Changes["title"]; changes.Before != "Item" || changes.After != "Renamed" {
+			t.Fatalf("the update recorded the title change %+v", changes)
+		}
+
+		activity, _, err = repos.Activity.GetByItem(ctx, stranger, itemId, todolist_app.Page{})
+		mustOk(t, err)
+		if len(activity) != 0 {
+			t.Fatalf("a stranger sees %d entries of the item", len(activity))
+		}
+	})
+}
+
+func trashKeys(entries []todolist_app.TrashEntry) []string {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = fmt.Sprintf("%s:%d", entry.Kind, entry.Id)
+	}
+
+	return keys
+}
+
+func testTrash(t *testing.T, repos *Repository) {
+	ctx := context.Background()
+
+	t.Run("restore item", func(t *testing.T) {
+		owner, _ := createUser(t, repos)
+		viewer, viewerName := createUser(t, repos)
+		listId := createList(t, repos, owner, "List")
+		parentId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Parent"})
+		subtaskId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Subtask", ParentId: &parentId})
+		earlierId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Earlier", ParentId: &parentId})
+		_, err := repos.SaveMember(ctx, owner, listId, viewerName, todolist_app.RoleViewer)
+		mustOk(t, err)
+
+		mustOk(t, repos.TodoItem.Delete(ctx, owner, earlierId))
+		mustOk(t, repos.TodoItem.Delete(ctx, owner, parentId))
+
+		// the subtasks come back with their parent and are not listed on their own
+		entries, err := repos.Trash.GetAll(ctx, owner)
+		mustOk(t, err)
+		if got, want := trashKeys(entries), []string{fmt.Sprintf("item:%d", parentId)}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("trash holds %v, want %v", got, want)
+		}
+
+		entries, err = repos.Trash.GetAll(ctx, viewer)
+		mustOk(t, err)
+		if len(entries) != 0 {
+			t.Fatalf("the trash of a viewer holds %v", trashKeys(entries))
+		}
+
+		wantCode(t, repos.RestoreItem(ctx, owner, subtaskId), todolist_app.CodeConflict)
+		wantCode(t, repos.RestoreItem(ctx, viewer, parentId), todolist_app.CodeNotFound)
+
+		mustOk(t, repos.RestoreItem(ctx, owner, parentId))
+		wantCode(t, repos.RestoreItem(ctx, owner, parentId), todolist_app.CodeNotFound)
+
+		_, err = repos.TodoItem.GetById(ctx, viewer, parentId)
+		mustOk(t, err)
+		_, err = repos.TodoItem.GetById(ctx, viewer, subtaskId)
+		mustOk(t, err)
+
+		// the subtask deleted before its parent stays in the trash
+		_, err = repos.TodoItem.GetById(ctx, owner, earlierId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+		mustOk(t, repos.RestoreItem(ctx, owner, earlierId))
+	})
+
+	t.Run("restore list", func(t *testing.T) {
+		owner, _ := createUser(t, repos)
+		editor, editorName := createUser(t, repos)
+		listId := createList(t, repos, owner, "List")
+		itemId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Item"})
+		earlierId := createItem(t, repos, owner, listId, todolist_app.TodoItem{Title: "Earlier"})
+		_, err := repos.SaveMember(ctx, owner, listId, editorName, todolist_app.RoleEditor)
+		mustOk(t, err)
+
+		mustOk(t, repos.TodoItem.Delete(ctx, owner, earlierId))
+		mustOk(t, repos.TodoList.Delete(ctx, owner, listId))
+
+		// the items of a deleted list come back with it
+		entries, err := repos.Trash.GetAll(ctx, owner)
+		mustOk(t, err)
+		if got, want := trashKeys(entries), []string{fmt.Sprintf("list:%d", listId)}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("trash holds %v, want %v", got, want)
+		}
+
+		entries, err = repos.Trash.GetAll(ctx, editor)
+		mustOk(t, err)
+		if len(entries) != 0 {
+			t.Fatalf("the trash of an editor holds %v", trashKeys(entries))
+		}
+
+		wantCode(t, repos.RestoreItem(ctx, owner, itemId), todolist_app.CodeConflict)
+		wantCode(t, repos.RestoreList(ctx, editor, listId), todolist_app.CodeNotFound)
+
+		mustOk(t, repos.RestoreList(ctx, owner, listId))
+		wantCode(t, repos.RestoreList(ctx, owner, listId), todolist_app.CodeNotFound)
+
+		_, err = repos.TodoList.GetById(ctx, owner, listId)
+		mustOk(t, err)
+		_, err = repos.TodoItem.GetById(ctx, editor, itemId)
+		mustOk(t, err)
+
+		// the item deleted before the list stays in the trash
+		entries, err = repos.Trash.GetAll(ctx, owner)
+		mustOk(t, err)
+		if got, want := trashKeys(entries), []string{fmt.Sprintf("item:%d", earlierId)}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("trash holds %v after the restore, want %v", got, want)
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		userId, _ := createUser(t, repos)
+		listId := createList(t, repos, userId, "List")
+		itemId := createItem(t, repos, userId, listId, todolist_app.TodoItem{Title: "Old"})
+		mustOk(t, repos.TodoItem.Delete(ctx, userId, itemId))
+
+		purged, err := repos.Purge(ctx, time.Now().Add(time.Second))
+		mustOk(t, err)
+		if purged < 1 {
+			t.Fatalf("Purge removed %d records, want the deleted item", purged)
+		}
+
+		_, err = repos.TodoItem.GetById(ctx, userId, itemId)
+		wantCode(t, err, todolist_app.CodeNotFound)
+
+		// the history of the list is kept, the entries of the purged item just lose their item
+		activity, _, err := repos.Activity.GetByList(ctx, listId, todolist_app.Page{})
+		mustOk(t, err)
+
+		var actions []string
+		for _, a := range activity {
+			if a.Entity != todolist_app.ActivityEntityItem {
+				continue
+			}
+
+			if a.ItemId != nil {
+				t.Fatalf("entry %d still refers to the purged item %d", a.Id, *a.ItemId)
+			}
+			actions = append(actions, a.Action)
+		}
+
+		want := []string{todolist_app.ActionDeleted, todolist_app.ActionCreated}
+		if fmt.Sprint(actions) != fmt.Sprint(want) {
+			t.Fatalf("item entries of the list are %v after the purge, want %v", actions, want)
+		}
+	})
+}
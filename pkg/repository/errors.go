@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	todolist_app "todolist-app"
+)
+
+var (
+	errListModified = todolist_app.NewPreconditionFailedError("list has been modified since the given version")
+	errItemModified = todolist_app.NewPreconditionFailedError("item has been modified since the given version")
+	errLastOwner    = todolist_app.NewConflictError("list must keep at least one owner")
+)
+
+// translateError turns driver errors into domain errors, keeping the original error wrapped.
+// entity names the affected record in the client-facing message, e.g. "list not found".
+func translateError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &todolist_app.Error{Code: todolist_app.CodeNotFound, Message: entity + " not found", Err: err}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "23": // integrity constraint violation
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				return &todolist_app.Error{Code: todolist_app.CodeConflict, Message: entity + " already exists", Err: err}
+			case "foreign_key_violation":
+				return &todolist_app.Error{Code: todolist_app.CodeNotFound, Message: "referenced record not found", Err: err}
+			default:
+				return &todolist_app.Error{Code: todolist_app.CodeValidation, Message: "invalid " + entity, Err: err}
+			}
+		case "22": // data exception, e.g. a value too long for its column
+			return &todolist_app.Error{Code: todolist_app.CodeValidation, Message: "invalid " + entity, Err: err}
+		}
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return &todolist_app.Error{Code: todolist_app.CodeConflict, Message: entity + " already exists", Err: err}
+		case sqlite3.ErrConstraintForeignKey:
+			return &todolist_app.Error{Code: todolist_app.CodeNotFound, Message: "referenced record not found", Err: err}
+		default: // the checks standing in for varchar lengths among others
+			return &todolist_app.Error{Code: todolist_app.CodeValidation, Message: "invalid " + entity, Err: err}
+		}
+	}
+
+	return err
+}
+
+// checkRowsAffected reports a not found error when a statement did not touch any row,
+// which is the case for records that do not exist or are not visible to the user.
+func checkRowsAffected(res sql.Result, entity string) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return todolist_app.NewNotFoundError(entity + " not found")
+	}
+
+	return nil
+}
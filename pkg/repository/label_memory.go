@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	todolist_app "todolist-app"
+)
+
+type LabelMemory struct {
+	db *MemoryDB
+}
+
+func NewLabelMemory(db *MemoryDB) *LabelMemory {
+	return &LabelMemory{db: db}
+}
+
+func (r *LabelMemory) Create(ctx context.Context, userId int, label todolist_app.Label) (int, error) {
+	var id int
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		if err := tx.checkLabel(userId, 0, label.Name, label.Color); err != nil {
+			return err
+		}
+
+		id = tx.nextId()
+		tx.labels[id] = memoryLabel{
+			Label: todolist_app.Label{
+				Id:        id,
+				Name:      label.Name,
+				Color:     label.Color,
+				CreatedAt: tx.now,
+				UpdatedAt: tx.now,
+			},
+			UserId: userId,
+		}
+
+		return nil
+	})
+
+	return id, err
+}
+
+// checkLabel applies the column sizes and the unique (user_id, name) constraint of the labels table.
+func (s *memoryState) checkLabel(userId, labelId int, name, color string) error {
+	if err := checkLength("label", 255, name); err != nil {
+		return err
+	}
+
+	if err := checkLength("label", 16, color); err != nil {
+		return err
+	}
+
+	for _, l := range s.labels {
+		if l.UserId == userId && l.Name == name && l.Id != labelId {
+			return todolist_app.NewConflictError("label already exists")
+		}
+	}
+
+	return nil
+}
+
+func (r *LabelMemory) GetAll(ctx context.Context, userId int) ([]todolist_app.Label, error) {
+	var labels []todolist_app.Label
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, l := range s.labels {
+			if l.UserId == userId {
+				labels = append(labels, l.Label)
+			}
+		}
+
+		return nil
+	})
+
+	sortLabels(labels)
+
+	return labels, err
+}
+
+func sortLabels(labels []todolist_app.Label) {
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Name != labels[j].Name {
+			return labels[i].Name < labels[j].Name
+		}
+		return labels[i].Id < labels[j].Id
+	})
+}
+
+func (r *LabelMemory) GetById(ctx context.Context, userId, labelId int) (todolist_app.Label, error) {
+	var label todolist_app.Label
+	err := r.db.read(ctx, func(s *memoryState) error {
+		l, ok := s.labels[labelId]
+		if !ok || l.UserId != userId {
+			return notFound("label")
+		}
+
+		label = l.Label
+
+		return nil
+	})
+
+	return label, err
+}
+
+func (r *LabelMemory) Update(ctx context.Context, userId, labelId int, input todolist_app.UpdateLabelInput) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		l, ok := tx.labels[labelId]
+		if !ok || l.UserId != userId {
+			return notFound("label")
+		}
+
+		if input.Name != nil {
+			l.Name = *input.Name
+		}
+		if input.Color != nil {
+			l.Color = *input.Color
+		}
+
+		if err := tx.checkLabel(userId, labelId, l.Name, l.Color); err != nil {
+			return err
+		}
+
+		l.UpdatedAt = tx.now
+		tx.labels[labelId] = l
+
+		return nil
+	})
+}
+
+func (r *LabelMemory) Delete(ctx context.Context, userId, labelId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		l, ok := tx.labels[labelId]
+		if !ok || l.UserId != userId {
+			return notFound("label")
+		}
+
+		delete(tx.labels, labelId)
+		for il := range tx.itemsLabels {
+			if il.LabelId == labelId {
+				delete(tx.itemsLabels, il)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetByItem returns the user's labels on an item of one of their lists.
+func (r *LabelMemory) GetByItem(ctx context.Context, userId, itemId int) ([]todolist_app.Label, error) {
+	var labels []todolist_app.Label
+	err := r.db.read(ctx, func(s *memoryState) error {
+		item, ok := s.items[itemId]
+		if !ok {
+			return nil
+		}
+
+		if _, ok := s.members[memoryMember{ListId: item.ListId, UserId: userId}]; !ok {
+			return nil
+		}
+
+		for il := range s.itemsLabels {
+			if l := s.labels[il.LabelId]; il.ItemId == itemId && l.UserId == userId {
+				labels = append(labels, l.Label)
+			}
+		}
+
+		return nil
+	})
+
+	sortLabels(labels)
+
+	return labels, err
+}
+
+// Attach links a label to an item. Attaching a label twice is not an error.
+func (r *LabelMemory) Attach(ctx context.Context, itemId, labelId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		if _, ok := tx.items[itemId]; !ok {
+			return todolist_app.NewNotFoundError("referenced record not found")
+		}
+
+		if _, ok := tx.labels[labelId]; !ok {
+			return todolist_app.NewNotFoundError("referenced record not found")
+		}
+
+		tx.itemsLabels[memoryItemLabel{ItemId: itemId, LabelId: labelId}] = true
+
+		return nil
+	})
+}
+
+func (r *LabelMemory) Detach(ctx context.Context, userId, itemId, labelId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		key := memoryItemLabel{ItemId: itemId, LabelId: labelId}
+		if l, ok := tx.labels[labelId]; !ok || l.UserId != userId || !tx.itemsLabels[key] {
+			return notFound("label")
+		}
+
+		delete(tx.itemsLabels, key)
+
+		return nil
+	})
+}
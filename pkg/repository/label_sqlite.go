@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type LabelSQLite struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewLabelSQLite(db *sqlx.DB, timeout time.Duration) *LabelSQLite {
+	return &LabelSQLite{db: db, timeout: timeout}
+}
+
+func (r *LabelSQLite) Create(ctx context.Context, userId int, label todolist_app.Label) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var id int
+	now := sqliteNow()
+	query := fmt.Sprintf("INSERT INTO %s (user_id, name, color, created_at, updated_at) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		labelsTable)
+	row := r.db.QueryRowContext(ctx, query, userId, label.Name, label.Color, now, now)
+	if err := row.Scan(&id); err != nil {
+		return 0, translateError(err, "label")
+	}
+
+	return id, nil
+}
+
+func (r *LabelSQLite) GetAll(ctx context.Context, userId int) ([]todolist_app.Label, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var labels []todolist_app.Label
+	query := fmt.Sprintf("SELECT id, name, color, created_at, updated_at FROM %s WHERE user_id = ? ORDER BY name, id", labelsTable)
+	err := r.db.SelectContext(ctx, &labels, query, userId)
+
+	return labels, err
+}
+
+func (r *LabelSQLite) GetById(ctx context.Context, userId, labelId int) (todolist_app.Label, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var label todolist_app.Label
+	query := fmt.Sprintf("SELECT id, name, color, created_at, updated_at FROM %s WHERE id = ? AND user_id = ?", labelsTable)
+	err := r.db.GetContext(ctx, &label, query, labelId, userId)
+
+	return label, translateError(err, "label")
+}
+
+func (r *LabelSQLite) Update(ctx context.Context, userId, labelId int, input todolist_app.UpdateLabelInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	setValues := []string{"updated_at=?"}
+	args := []interface{}{sqliteNow()}
+
+	if input.Name != nil {
+		setValues = append(setValues, "name=?")
+		args = append(args, *input.Name)
+	}
+
+	if input.Color != nil {
+		setValues = append(setValues, "color=?")
+		args = append(args, *input.Color)
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ? AND user_id = ?", labelsTable, setQuery)
+	args = append(args, labelId, userId)
+
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translateError(err, "label")
+	}
+
+	return checkRowsAffected(res, "label")
+}
+
+func (r *LabelSQLite) Delete(ctx context.Context, userId, labelId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND user_id = ?", labelsTable)
+	res, err := r.db.ExecContext(ctx, query, labelId, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res, "label")
+}
+
+// GetByItem returns the user's labels on an item of one of their lists.
+func (r *LabelSQLite) GetByItem(ctx context.Context, userId, itemId int) ([]todolist_app.Label, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var labels []todolist_app.Label
+	query := fmt.Sprintf(`SELECT l.id, l.name, l.color, l.created_at, l.updated_at FROM %s l INNER JOIN %s il on il.label_id = l.id
+									INNER JOIN %s li on li.item_id = il.item_id INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE il.item_id = ?1 AND l.user_id = ?2 AND ul.user_id = ?2 ORDER BY l.name, l.id`,
+		labelsTable, itemsLabelsTable, listsItemsTable, usersListsTable)
+	err := r.db.SelectContext(ctx, &labels, query, itemId, userId)
+
+	return labels, err
+}
+
+// Attach links a label to an item. Attaching a label twice is not an error.
+func (r *LabelSQLite) Attach(ctx context.Context, itemId, labelId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf("INSERT INTO %s (item_id, label_id) VALUES (?, ?) ON CONFLICT (item_id, label_id) DO NOTHING",
+		itemsLabelsTable)
+	_, err := r.db.ExecContext(ctx, query, itemId, labelId)
+
+	return translateError(err, "label")
+}
+
+func (r *LabelSQLite) Detach(ctx context.Context, userId, itemId, labelId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE item_id = ? AND label_id = ?
+									AND label_id IN (SELECT id FROM %s WHERE user_id = ?)`,
+		itemsLabelsTable, labelsTable)
+	res, err := r.db.ExecContext(ctx, query, itemId, labelId, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(res, "label")
+}
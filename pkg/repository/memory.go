@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+	todolist_app "todolist-app"
+)
+
+// MemoryDB keeps the data of the in-memory repositories, which stand in for Postgres in tests and
+// local development. A write works on a copy of the data that replaces the original only when the write
+// succeeds, so writes are all-or-nothing like the transactions of the Postgres repositories.
+type MemoryDB struct {
+	mu    sync.Mutex
+	state *memoryState
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{state: &memoryState{
+		users:         make(map[int]todolist_app.User),
+		refreshTokens: make(map[int]todolist_app.RefreshToken),
+		lists:         make(map[int]memoryList),
+		members:       make(map[memoryMember]todolist_app.Role),
+		items:         make(map[int]memoryItem),
+		labels:        make(map[int]memoryLabel),
+		itemsLabels:   make(map[memoryItemLabel]bool),
+	}}
+}
+
+type memoryList struct {
+	todolist_app.TodoList
+	DeletedAt *time.Time
+}
+
+type memoryItem struct {
+	todolist_app.TodoItem
+	DeletedAt *time.Time
+}
+
+type memoryMember struct {
+	ListId int
+	UserId int
+}
+
+type memoryLabel struct {
+	todolist_app.Label
+	UserId int
+}
+
+type memoryItemLabel struct {
+	ItemId  int
+	LabelId int
+}
+
+// memoryState is one version of the data. Records are stored by value, so copying the maps
+// is enough to get an independent copy.
+type memoryState struct {
+	// now is the time of the running write, shared by everything it changes like now() in a transaction
+	now    time.Time
+	lastId int
+
+	users         map[int]todolist_app.User
+	refreshTokens map[int]todolist_app.RefreshToken
+	lists         map[int]memoryList
+	members       map[memoryMember]todolist_app.Role
+	items         map[int]memoryItem
+	labels        map[int]memoryLabel
+	itemsLabels   map[memoryItemLabel]bool
+	activity      []todolist_app.Activity
+}
+
+func (s *memoryState) clone() *memoryState {
+	return &memoryState{
+		lastId:        s.lastId,
+		users:         copyMap(s.users),
+		refreshTokens: copyMap(s.refreshTokens),
+		lists:         copyMap(s.lists),
+		members:       copyMap(s.members),
+		items:         copyMap(s.items),
+		labels:        copyMap(s.labels),
+		itemsLabels:   copyMap(s.itemsLabels),
+		// appending to a slice without spare capacity leaves the original alone
+		activity: s.activity[:len(s.activity):len(s.activity)],
+	}
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
+
+// nextId hands out ids from a single sequence, so ids are unique across all records.
+func (s *memoryState) nextId() int {
+	s.lastId++
+	return s.lastId
+}
+
+// read runs fn on the current data, unless ctx is already done.
+func (db *MemoryDB) read(ctx context.Context, fn func(s *memoryState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return fn(db.state)
+}
+
+// write runs fn on a copy of the data and keeps the copy if fn succeeds, unless ctx is already done.
+func (db *MemoryDB) write(ctx context.Context, fn func(tx *memoryState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx := db.state.clone()
+	// timestamptz keeps microseconds
+	tx.now = time.Now().Truncate(time.Microsecond)
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	db.state = tx
+
+	return nil
+}
+
+// notFound returns the error translateError gives for a missing row.
+func notFound(entity string) error {
+	return translateError(sql.ErrNoRows, entity)
+}
+
+// checkLength reports values that do not fit their varchar column like Postgres would.
+func checkLength(entity string, limit int, values ...string) error {
+	for _, v := range values {
+		if len([]rune(v)) > limit {
+			return todolist_app.NewValidationError("invalid " + entity)
+		}
+	}
+
+	return nil
+}
+
+// role returns the role of the user in a list that is not deleted.
+func (s *memoryState) role(userId, listId int) (todolist_app.Role, bool) {
+	list, ok := s.lists[listId]
+	if !ok || list.DeletedAt != nil {
+		return "", false
+	}
+
+	role, ok := s.members[memoryMember{ListId: listId, UserId: userId}]
+
+	return role, ok
+}
+
+// visibleItem returns an item that is not deleted in a list the user is a member of.
+// Like the joins of the Postgres queries, it does not check whether the list is deleted.
+func (s *memoryState) visibleItem(userId, itemId int) (memoryItem, todolist_app.Role, bool) {
+	item, ok := s.items[itemId]
+	if !ok || item.DeletedAt != nil {
+		return item, "", false
+	}
+
+	role, ok := s.members[memoryMember{ListId: item.ListId, UserId: userId}]
+
+	return item, role, ok
+}
+
+// itemView returns the item as it is read, with the progress of its subtasks.
+func (s *memoryState) itemView(item memoryItem) todolist_app.TodoItem {
+	view := item.TodoItem
+	view.Progress = todolist_app.Progress{}
+	for _, sub := range s.items {
+		if sub.ParentId != nil && *sub.ParentId == item.Id && sub.DeletedAt == nil {
+			view.Progress.Total++
+			if sub.Done {
+				view.Progress.Done++
+			}
+		}
+	}
+
+	return view
+}
+
+func (s *memoryState) recordActivity(entry activityEntry) {
+	var itemId *int
+	if entry.ItemId != nil {
+		id := *entry.ItemId
+		itemId = &id
+	}
+
+	s.recordActivityAt(entry.ListId, itemId, entry)
+}
+
+// recordActivityAt stores an entry the way it reads back from the jsonb column.
+func (s *memoryState) recordActivityAt(listId int, itemId *int, entry activityEntry) {
+	changes := make(todolist_app.Changes)
+	if data, err := json.Marshal(entry.Changes); err == nil {
+		json.Unmarshal(data, &changes)
+	}
+
+	actorId := entry.ActorId
+	s.activity = append(s.activity, todolist_app.Activity{
+		Id:        s.nextId(),
+		ListId:    listId,
+		ItemId:    itemId,
+		ActorId:   &actorId,
+		Entity:    entry.Entity,
+		Action:    entry.Action,
+		Changes:   changes,
+		CreatedAt: s.now,
+	})
+}
+
+// recordItemsActivity records the same entry for several items, each in its current list.
+func (s *memoryState) recordItemsActivity(itemIds []int, entry activityEntry) {
+	for _, id := range itemIds {
+		itemId := id
+		s.recordActivityAt(s.items[id].ListId, &itemId, entry)
+	}
+}
+
+func NewMemoryRepository(db *MemoryDB) *Repository {
+	return &Repository{
+		Authorization: NewAuthMemory(db),
+		TodoList:      NewTodoListMemory(db),
+		TodoItem:      NewTodoItemMemory(db),
+		Label:         NewLabelMemory(db),
+		Search:        NewSearchMemory(db),
+		Trash:         NewTrashMemory(db),
+		Activity:      NewActivityMemory(db),
+	}
+}
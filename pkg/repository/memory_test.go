@@ -0,0 +1,7 @@
+package repository
+
+import "testing"
+
+func TestMemoryConformance(t *testing.T) {
+	testConformance(t, NewMemoryRepository(NewMemoryDB()))
+}
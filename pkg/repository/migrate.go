@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"todolist-app/schema"
+)
+
+// migrationsTable is the table golang-migrate keeps the schema version in, so databases migrated
+// with its CLI carry on with the Migrator and the other way around. It holds at most one row.
+const migrationsTable = "schema_migrations"
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+type MigrationStatus struct {
+	// Version is the last applied migration, 0 when there is none
+	Version int64
+	// Dirty is set when a migration failed half-way, which golang-migrate can leave behind
+	Dirty      bool
+	Migrations []Migration
+}
+
+// Migrator applies the migrations of a schema directory to a Postgres or SQLite database.
+// Every migration runs in a transaction together with the version change, under a lock
+// that keeps instances starting at the same time from applying it twice.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sqlx.DB, files fs.FS) (*Migrator, error) {
+	migrations, err := readMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// OpenMigrator opens the database cfg selects to migrate it with the embedded migrations of its driver.
+// Close releases the database.
+func OpenMigrator(cfg Config) (*Migrator, error) {
+	var db *sqlx.DB
+	var files fs.FS
+	var err error
+
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		db, err = NewPostgresDB(cfg)
+		files = schema.Postgres
+	case DriverSQLite:
+		db, err = NewSQLiteDB(cfg)
+		files = schema.SQLite
+	case DriverMemory:
+		return nil, errors.New("the memory storage has no schema to migrate")
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMigrator(db, files)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// autoMigrate applies the pending migrations when cfg.AutoMigrate is set.
+func autoMigrate(cfg Config, db *sqlx.DB, files fs.FS) error {
+	if !cfg.AutoMigrate {
+		return nil
+	}
+
+	m, err := NewMigrator(db, files)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		return fmt.Errorf("migrating the database: %w", err)
+	}
+
+	logrus.Infof("applied %d migrations", applied)
+
+	return nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// readMigrations collects the <version>_<name>.up.sql and .down.sql files, ordered by version.
+func readMigrations(files fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, path := range paths {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(path, ".sql"), ".")
+		version, name, _ := strings.Cut(base, "_")
+		number, err := strconv.ParseInt(version, 10, 64)
+		if !ok || err != nil || number <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.up.sql or .down.sql", path)
+		}
+
+		data, err := fs.ReadFile(files, path)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[number]
+		if !ok {
+			migration = &Migration{Version: number, Name: name}
+			byVersion[number] = migration
+		}
+
+		if direction == "up" {
+			migration.up = string(data)
+		} else {
+			migration.down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies the pending migrations and returns how many it applied. A database whose schema was
+// applied by hand has no version yet, Up refuses to run the migrations over it until one is forced.
+func (m *Migrator) Up() (int, error) {
+	applied := 0
+	for {
+		done, err := m.step(func(tx *sqlx.Tx, version int64) (bool, error) {
+			next := m.find(func(migration Migration) bool { return migration.Version > version })
+			if next == nil {
+				return true, nil
+			}
+
+			if version == 0 {
+				exists, err := hasTable(tx, usersTable)
+				if err != nil {
+					return false, err
+				}
+
+				if exists {
+					return false, fmt.Errorf("the database has a schema but no migration version, record the last migration "+
+						"applied to it with migrate force <version>, e.g. migrate force %d, then run migrate up", next.Version)
+				}
+			}
+
+			if _, err := tx.Exec(next.up); err != nil {
+				return false, fmt.Errorf("migration %d_%s: %w", next.Version, next.Name, err)
+			}
+
+			return false, setVersion(tx, next.Version)
+		})
+		if err != nil || done {
+			return applied, err
+		}
+		applied++
+	}
+}
+
+// Down undoes the last steps migrations, fewer if the schema runs out of them, and returns how many it undid.
+func (m *Migrator) Down(steps int) (int, error) {
+	undone := 0
+	for undone < steps {
+		done, err := m.step(func(tx *sqlx.Tx, version int64) (bool, error) {
+			if version == 0 {
+				return true, nil
+			}
+
+			current := m.find(func(migration Migration) bool { return migration.Version == version })
+			if current == nil {
+				return false, fmt.Errorf("there is no migration for the current version %d", version)
+			}
+
+			if current.down == "" {
+				return false, fmt.Errorf("migration %d_%s cannot be undone", current.Version, current.Name)
+			}
+
+			if _, err := tx.Exec(current.down); err != nil {
+				return false, fmt.Errorf("migration %d_%s: %w", current.Version, current.Name, err)
+			}
+
+			var previous int64
+			for _, migration := range m.migrations {
+				if migration.Version < version {
+					previous = migration.Version
+				}
+			}
+
+			return false, setVersion(tx, previous)
+		})
+		if err != nil || done {
+			return undone, err
+		}
+		undone++
+	}
+
+	return undone, nil
+}
+
+// Force records version as the current one and clears the dirty flag without running any migration,
+// to recover from a migration that failed half-way or to take over a schema applied by hand.
+// Version 0 records that no migration is applied.
+func (m *Migrator) Force(version int64) error {
+	if version != 0 && m.find(func(migration Migration) bool { return migration.Version == version }) == nil {
+		return fmt.Errorf("there is no migration %d", version)
+	}
+
+	if err := m.createTable(); err != nil {
+		return err
+	}
+
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := setVersion(tx, version); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) Status() (MigrationStatus, error) {
+	status := MigrationStatus{Migrations: m.migrations}
+	if err := m.createTable(); err != nil {
+		return status, err
+	}
+
+	var err error
+	status.Version, status.Dirty, err = getVersion(m.db)
+
+	return status, err
+}
+
+// step runs fn with the current version in a transaction that holds the version lock.
+// fn reports whether there is nothing left to do.
+func (m *Migrator) step(fn func(tx *sqlx.Tx, version int64) (bool, error)) (bool, error) {
+	if err := m.createTable(); err != nil {
+		return false, err
+	}
+
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+
+	// SQLite transactions lock the whole database as they begin
+	if m.db.DriverName() == "postgres" {
+		if _, err := tx.Exec(fmt.Sprintf("LOCK TABLE %s IN EXCLUSIVE MODE", migrationsTable)); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	version, dirty, err := getVersion(tx)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if dirty {
+		tx.Rollback()
+		return false, fmt.Errorf("migration %d failed half-way, fix the schema and force a version", version)
+	}
+
+	done, err := fn(tx, version)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return done, tx.Commit()
+}
+
+func (m *Migrator) find(match func(Migration) bool) *Migration {
+	for i := range m.migrations {
+		if match(m.migrations[i]) {
+			return &m.migrations[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) createTable() error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint not null primary key, dirty boolean not null)",
+		migrationsTable)
+	_, err := m.db.Exec(query)
+
+	return err
+}
+
+// hasTable reports whether the table exists in the database tx runs on.
+func hasTable(tx *sqlx.Tx, table string) (bool, error) {
+	query := "SELECT to_regclass($1) IS NOT NULL"
+	if tx.DriverName() != "postgres" {
+		query = "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)"
+	}
+
+	var exists bool
+	err := tx.Get(&exists, query, table)
+
+	return exists, err
+}
+
+func getVersion(q sqlx.Queryer) (int64, bool, error) {
+	var row struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+	err := sqlx.Get(q, &row, fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", migrationsTable))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return row.Version, row.Dirty, err
+}
+
+func setVersion(tx *sqlx.Tx, version int64) error {
+	if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s", migrationsTable)); err != nil {
+		return err
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	query := tx.Rebind(fmt.Sprintf("INSERT INTO %s (version, dirty) VALUES (?, false)", migrationsTable))
+	_, err := tx.Exec(query, version)
+
+	return err
+}
@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	todolist_app "todolist-app"
+)
+
+const defaultPageLimit = 50
+
+type sortColumn[T any] struct {
+	expr  string
+	cast  string
+	value func(T) string
+}
+
+var listSortColumns = map[string]sortColumn[todolist_app.TodoList]{
+	"id":    {"tl.id", "integer", func(l todolist_app.TodoList) string { return strconv.Itoa(l.Id) }},
+	"title": {"tl.title", "text", func(l todolist_app.TodoList) string { return l.Title }},
+	"created_at": {"tl.created_at", "timestamptz", func(l todolist_app.TodoList) string {
+		return l.CreatedAt.Format(time.RFC3339Nano)
+	}},
+	"updated_at": {"tl.updated_at", "timestamptz", func(l todolist_app.TodoList) string {
+		return l.UpdatedAt.Format(time.RFC3339Nano)
+	}},
+}
+
+var itemSortColumns = map[string]sortColumn[todolist_app.TodoItem]{
+	"id":    {"ti.id", "integer", func(i todolist_app.TodoItem) string { return strconv.Itoa(i.Id) }},
+	"title": {"ti.title", "text", func(i todolist_app.TodoItem) string { return i.Title }},
+	"done":  {"ti.done", "boolean", func(i todolist_app.TodoItem) string { return strconv.FormatBool(i.Done) }},
+	"priority": {"ti.priority", "smallint", func(i todolist_app.TodoItem) string {
+		return strconv.Itoa(int(i.Priority))
+	}},
+	"position": {"li.position", "double precision", func(i todolist_app.TodoItem) string {
+		return strconv.FormatFloat(i.Position, 'g', -1, 64)
+	}},
+	"created_at": {"ti.created_at", "timestamptz", func(i todolist_app.TodoItem) string {
+		return i.CreatedAt.Format(time.RFC3339Nano)
+	}},
+	"updated_at": {"ti.updated_at", "timestamptz", func(i todolist_app.TodoItem) string {
+		return i.UpdatedAt.Format(time.RFC3339Nano)
+	}},
+	"completed_at": {"COALESCE(ti.completed_at, '-infinity')", "timestamptz", func(i todolist_app.TodoItem) string {
+		if i.CompletedAt == nil {
+			return "-infinity"
+		}
+		return i.CompletedAt.Format(time.RFC3339Nano)
+	}},
+	"due_at": {"COALESCE(ti.due_at, 'infinity')", "timestamptz", func(i todolist_app.TodoItem) string {
+		if i.DueAt == nil {
+			return "infinity"
+		}
+		return i.DueAt.Format(time.RFC3339Nano)
+	}},
+}
+
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, todolist_app.NewValidationError("invalid cursor")
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, todolist_app.NewValidationError("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// keyset describes one page of a result ordered by a sort column with the row id as a tie-breaker.
+type keyset[T any] struct {
+	sort   string
+	column sortColumn[T]
+	desc   bool
+	limit  int
+	after  *cursor
+}
+
+func newKeyset[T any](columns map[string]sortColumn[T], page todolist_app.Page, defaultSort string) (keyset[T], error) {
+	k := keyset[T]{sort: page.Sort, limit: page.Limit}
+	if k.sort == "" {
+		k.sort = defaultSort
+	}
+
+	if k.limit <= 0 {
+		k.limit = defaultPageLimit
+	}
+
+	name := strings.TrimPrefix(k.sort, "-")
+	k.desc = name != k.sort
+
+	column, ok := columns[name]
+	if !ok {
+		return k, todolist_app.NewValidationError(fmt.Sprintf("unknown sort field %q", name))
+	}
+	k.column = column
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return k, err
+		}
+
+		if c.Sort != k.sort {
+			return k, todolist_app.NewValidationError("cursor does not match sort order")
+		}
+		k.after = &c
+	}
+
+	return k, nil
+}
+
+// where returns the condition selecting rows after the cursor, or an empty string for the first page.
+func (k keyset[T]) where(idExpr string, argId int) (string, []interface{}) {
+	if k.after == nil {
+		return "", nil
+	}
+
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+
+	return fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d)", k.column.expr, idExpr, op, argId, k.column.cast, argId+1),
+		[]interface{}{k.after.Value, k.after.Id}
+}
+
+// sqliteWhere is where for SQLite, which has no casts: the cursor value is bound with the type the column
+// is stored as. Timestamps are bound in UTC like sqliteNow, infinity and -infinity stay text, which sorts
+// after and before every stored time.
+func (k keyset[T]) sqliteWhere(idExpr string) (string, []interface{}) {
+	if k.after == nil {
+		return "", nil
+	}
+
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", k.column.expr, idExpr, op),
+		[]interface{}{sqliteValue(k.column.cast, k.after.Value), k.after.Id}
+}
+
+func sqliteValue(cast, value string) interface{} {
+	switch cast {
+	case "integer", "smallint":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "double precision":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "timestamptz":
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return sqliteTime(&t)
+		}
+	}
+
+	return value
+}
+
+func (k keyset[T]) orderBy(idExpr string) string {
+	direction := "ASC"
+	if k.desc {
+		direction = "DESC"
+	}
+
+	// one extra row tells whether there is a next page
+	return fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT %d", k.column.expr, direction, idExpr, direction, k.limit+1)
+}
+
+// next trims the extra row fetched by orderBy and returns the cursor of the following page, if any.
+func (k keyset[T]) next(rows []T, id func(T) int) ([]T, string) {
+	if len(rows) <= k.limit {
+		return rows, ""
+	}
+
+	rows = rows[:k.limit]
+	last := rows[len(rows)-1]
+
+	return rows, encodeCursor(cursor{Sort: k.sort, Value: k.column.value(last), Id: id(last)})
+}
+
+// page applies the keyset to rows held in memory: the rows are ordered like orderBy, the ones up to
+// the cursor are skipped like where does and the page is cut like next. Text is compared bytewise,
+// which can order differently from the collation of the database.
+func (k keyset[T]) page(rows []T, id func(T) int) ([]T, string) {
+	compare := func(a T, value string, aId int) int {
+		if c := compareValues(k.column.cast, k.column.value(a), value); c != 0 {
+			return c
+		}
+
+		return compareInts(id(a), aId)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		c := compare(rows[i], k.column.value(rows[j]), id(rows[j]))
+		if k.desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if k.after != nil {
+		start := len(rows)
+		for i, row := range rows {
+			c := compare(row, k.after.Value, k.after.Id)
+			if (k.desc && c < 0) || (!k.desc && c > 0) {
+				start = i
+				break
+			}
+		}
+		rows = rows[start:]
+	}
+
+	if len(rows) > k.limit+1 {
+		rows = rows[:k.limit+1]
+	}
+
+	return k.next(rows, id)
+}
+
+// compareValues compares two sort values of a column with the given SQL type.
+func compareValues(cast, a, b string) int {
+	switch cast {
+	case "integer", "smallint":
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return compareInts(x, y)
+	case "double precision":
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case "boolean":
+		x, _ := strconv.ParseBool(a)
+		y, _ := strconv.ParseBool(b)
+		return compareInts(boolInt(x), boolInt(y))
+	case "timestamptz":
+		return compareTimestamps(a, b)
+	}
+
+	return strings.Compare(a, b)
+}
+
+func compareTimestamps(a, b string) int {
+	rank := func(s string) (int, time.Time) {
+		switch s {
+		case "-infinity":
+			return -1, time.Time{}
+		case "infinity":
+			return 1, time.Time{}
+		}
+		t, _ := time.Parse(time.RFC3339Nano, s)
+		return 0, t
+	}
+
+	ra, ta := rank(a)
+	rb, tb := rank(b)
+	switch {
+	case ra != rb:
+		return compareInts(ra, rb)
+	case ta.Before(tb):
+		return -1
+	case ta.After(tb):
+		return 1
+	}
+
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func likePattern(q string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	todolist_app "todolist-app"
+)
+
+// Items are ordered by a fractional rank. New items are appended positionGap after the last one
+// and a moved item gets the midpoint of its new neighbours, so a move only rewrites one row and a reorder
+// the rows it puts out of their current order, until the neighbours get closer than minPositionGap
+// and the list is renumbered.
+const (
+	positionGap    = 1024
+	minPositionGap = 1e-6
+)
+
+type itemPosition struct {
+	ItemId   int     `db:"item_id"`
+	Position float64 `db:"position"`
+}
+
+// placeItem returns the positions to store for moving itemId next to the anchor item of input.
+// positions must hold every item of the list ordered by position.
+func placeItem(positions []itemPosition, itemId int, input todolist_app.ItemPositionInput) ([]itemPosition, error) {
+	anchorId, before := input.Anchor()
+
+	rest := make([]itemPosition, 0, len(positions))
+	for _, p := range positions {
+		if p.ItemId != itemId {
+			rest = append(rest, p)
+		}
+	}
+
+	idx := -1
+	for i, p := range rest {
+		if p.ItemId == anchorId {
+			idx = i
+			break
+		}
+	}
+
+	if idx < 0 {
+		return nil, todolist_app.NewValidationError(fmt.Sprintf("item %d is not in the same list", anchorId))
+	}
+
+	insertAt := idx
+	if !before {
+		insertAt = idx + 1
+	}
+
+	switch {
+	case insertAt == 0:
+		return []itemPosition{{ItemId: itemId, Position: rest[0].Position - positionGap}}, nil
+	case insertAt == len(rest):
+		return []itemPosition{{ItemId: itemId, Position: rest[len(rest)-1].Position + positionGap}}, nil
+	}
+
+	lo, hi := rest[insertAt-1].Position, rest[insertAt].Position
+	if hi-lo >= minPositionGap {
+		return []itemPosition{{ItemId: itemId, Position: lo + (hi-lo)/2}}, nil
+	}
+
+	ids := make([]int, 0, len(positions))
+	current := make(map[int]float64, len(positions))
+	for i, p := range rest {
+		if i == insertAt {
+			ids = append(ids, itemId)
+		}
+		ids = append(ids, p.ItemId)
+		current[p.ItemId] = p.Position
+	}
+
+	return changedPositions(current, renumber(ids)), nil
+}
+
+// orderItems returns the positions to store for putting itemIds first, in the given order, followed by
+// the remaining items of the list in their current order. The remaining items keep their positions and
+// so do the listed items already in order before them, the others get ranks between their new neighbours.
+// positions must hold every item of the list ordered by position.
+func orderItems(positions []itemPosition, itemIds []int) ([]itemPosition, error) {
+	current := make(map[int]float64, len(positions))
+	for _, p := range positions {
+		current[p.ItemId] = p.Position
+	}
+
+	seen := make(map[int]bool, len(itemIds))
+	for _, id := range itemIds {
+		if _, ok := current[id]; !ok {
+			return nil, todolist_app.NewValidationError(fmt.Sprintf("item %d is not in the list", id))
+		}
+
+		if seen[id] {
+			return nil, todolist_app.NewValidationError(fmt.Sprintf("item %d is listed twice", id))
+		}
+		seen[id] = true
+	}
+
+	ids := make([]int, 0, len(positions))
+	ids = append(ids, itemIds...)
+	bound, bounded := 0.0, false
+	for _, p := range positions {
+		if !seen[p.ItemId] {
+			if !bounded {
+				bound, bounded = p.Position, true
+			}
+			ids = append(ids, p.ItemId)
+		}
+	}
+
+	// the listed items have to end up before the first remaining one
+	var below []int
+	for i, id := range itemIds {
+		if !bounded || current[id] < bound {
+			below = append(below, i)
+		}
+	}
+
+	kept := make([]bool, len(itemIds))
+	for _, i := range increasingRun(below, func(i int) float64 { return current[itemIds[i]] }) {
+		kept[i] = true
+	}
+
+	ordered := make([]itemPosition, len(itemIds))
+	for start := 0; start < len(itemIds); {
+		if kept[start] {
+			ordered[start] = itemPosition{ItemId: itemIds[start], Position: current[itemIds[start]]}
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(itemIds) && !kept[end] {
+			end++
+		}
+
+		hasLo, hasHi := start > 0, end < len(itemIds) || bounded
+		var lo, hi float64
+		if hasLo {
+			lo = ordered[start-1].Position
+		}
+		if end < len(itemIds) {
+			hi = current[itemIds[end]]
+		} else {
+			hi = bound
+		}
+
+		n := float64(end - start + 1)
+		for i := start; i < end; i++ {
+			k := float64(i - start + 1)
+			var position float64
+			switch {
+			case hasLo && hasHi:
+				if (hi-lo)/n < minPositionGap {
+					return changedPositions(current, renumber(ids)), nil
+				}
+				position = lo + (hi-lo)*k/n
+			case hasLo:
+				position = lo + k*positionGap
+			case hasHi:
+				position = hi - (n-k)*positionGap
+			default:
+				position = k * positionGap
+			}
+			ordered[i] = itemPosition{ItemId: itemIds[i], Position: position}
+		}
+		start = end
+	}
+
+	return changedPositions(current, ordered), nil
+}
+
+// increasingRun returns the longest subsequence of indexes whose values increase, in order.
+func increasingRun(indexes []int, value func(i int) float64) []int {
+	// tails[l] ends the increasing run of length l+1 with the smallest last value seen so far,
+	// tails and previous hold offsets into indexes
+	var tails []int
+	previous := make([]int, len(indexes))
+	for n, i := range indexes {
+		l := sort.Search(len(tails), func(l int) bool { return value(indexes[tails[l]]) >= value(i) })
+		previous[n] = -1
+		if l > 0 {
+			previous[n] = tails[l-1]
+		}
+
+		if l == len(tails) {
+			tails = append(tails, n)
+		} else {
+			tails[l] = n
+		}
+	}
+
+	run := make([]int, len(tails))
+	if len(tails) == 0 {
+		return run
+	}
+
+	for l, n := len(tails)-1, tails[len(tails)-1]; l >= 0; l-- {
+		run[l] = indexes[n]
+		n = previous[n]
+	}
+
+	return run
+}
+
+// changedPositions leaves out the positions equal to the current ones, so only moved rows are written.
+func changedPositions(current map[int]float64, positions []itemPosition) []itemPosition {
+	changed := make([]itemPosition, 0, len(positions))
+	for _, p := range positions {
+		if position, ok := current[p.ItemId]; !ok || position != p.Position {
+			changed = append(changed, p)
+		}
+	}
+
+	return changed
+}
+
+func renumber(itemIds []int) []itemPosition {
+	positions := make([]itemPosition, len(itemIds))
+	for i, id := range itemIds {
+		positions[i] = itemPosition{ItemId: id, Position: float64(i+1) * positionGap}
+	}
+
+	return positions
+}
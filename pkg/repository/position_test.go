@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	todolist_app "todolist-app"
+)
+
+// listPositions returns the positions of items numbered from 1, in order.
+func listPositions(values ...float64) []itemPosition {
+	positions := make([]itemPosition, len(values))
+	for i, v := range values {
+		positions[i] = itemPosition{ItemId: i + 1, Position: v}
+	}
+
+	return positions
+}
+
+func TestOrderItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []itemPosition
+		itemIds   []int
+		want      []itemPosition
+	}{
+		{"already sorted", listPositions(1024, 2048, 3072), []int{1, 2, 3}, []itemPosition{}},
+		{"sorted prefix", listPositions(1024, 2048, 3072), []int{1, 2}, []itemPosition{}},
+		{"single item moved", listPositions(1024, 2048, 3072), []int{3, 1, 2}, []itemPosition{{3, 0}}},
+		{"single item moved to the middle", listPositions(1024, 2048, 3072), []int{1, 3, 2}, []itemPosition{{3, 1536}}},
+		{"reversed", listPositions(1024, 2048, 3072), []int{3, 2, 1}, []itemPosition{{3, -1024}, {2, 0}}},
+		{"listed before the rest", listPositions(1024, 2048, 3072), []int{3}, []itemPosition{{3, 0}}},
+		{"empty list", nil, nil, []itemPosition{}},
+		// there is no room left between the first two items, only the rows out of place are written
+		{"gap exhausted", listPositions(1024, 1024+1e-7, 2048), []int{1, 3, 2}, []itemPosition{{2, 3072}}},
+	}
+
+	for _, tc := range tests {
+		got, err := orderItems(tc.positions, tc.itemIds)
+		if err != nil {
+			t.Fatalf("%s: orderItems: %v", tc.name, err)
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Fatalf("%s: orderItems = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	for _, itemIds := range [][]int{{1, 4}, {2, 1, 2}} {
+		_, err := orderItems(listPositions(1024, 2048, 3072), itemIds)
+		if todolist_app.ErrorCodeOf(err) != todolist_app.CodeValidation {
+			t.Fatalf("orderItems(%v) returned %v, want a validation error", itemIds, err)
+		}
+	}
+}
+
+func TestPlaceItem(t *testing.T) {
+	before := func(id int) todolist_app.ItemPositionInput { return todolist_app.ItemPositionInput{BeforeId: &id} }
+	after := func(id int) todolist_app.ItemPositionInput { return todolist_app.ItemPositionInput{AfterId: &id} }
+
+	tests := []struct {
+		name      string
+		positions []itemPosition
+		itemId    int
+		input     todolist_app.ItemPositionInput
+		want      []itemPosition
+	}{
+		{"first", listPositions(1024, 2048, 3072), 3, before(1), []itemPosition{{3, 0}}},
+		{"last", listPositions(1024, 2048, 3072), 1, after(3), []itemPosition{{1, 4096}}},
+		{"between", listPositions(1024, 2048, 3072), 3, before(2), []itemPosition{{3, 1536}}},
+		{"after the moved item's neighbour", listPositions(1024, 2048, 3072), 1, after(2), []itemPosition{{1, 2560}}},
+		// the list is renumbered, but the items that keep their rank are not written
+		{"gap exhausted", listPositions(1024, 1024+1e-7, 2048), 3, after(1), []itemPosition{{3, 2048}, {2, 3072}}},
+	}
+
+	for _, tc := range tests {
+		got, err := placeItem(tc.positions, tc.itemId, tc.input)
+		if err != nil {
+			t.Fatalf("%s: placeItem: %v", tc.name, err)
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Fatalf("%s: placeItem = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	for _, anchorId := range []int{1, 4} {
+		_, err := placeItem(listPositions(1024, 2048, 3072), 1, before(anchorId))
+		if todolist_app.ErrorCodeOf(err) != todolist_app.CodeValidation {
+			t.Fatalf("placeItem before %d returned %v, want a validation error", anchorId, err)
+		}
+	}
+}
+
+func TestIncreasingRun(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   []int
+	}{
+		{nil, []int{}},
+		{[]float64{1, 2, 3}, []int{0, 1, 2}},
+		{[]float64{3, 2, 1}, []int{2}},
+		{[]float64{3, 1, 2, 5, 4}, []int{1, 2, 4}},
+		// equal values do not increase
+		{[]float64{1, 1, 2}, []int{1, 2}},
+	}
+
+	for _, tc := range tests {
+		indexes := make([]int, len(tc.values))
+		for i := range indexes {
+			indexes[i] = i
+		}
+
+		got := increasingRun(indexes, func(i int) float64 { return tc.values[i] })
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Fatalf("increasingRun(%v) = %v, want %v", tc.values, got, tc.want)
+		}
+	}
+}
@@ -3,23 +3,36 @@ package repository
 import (
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"time"
 )
 
 const (
-	usersTable      = "users"
-	todoListsTable  = "todo_lists"
-	usersListsTable = "users_lists"
-	todoItemsTable  = "todo_items"
-	listsItemsTable = "lists_items"
+	usersTable         = "users"
+	todoListsTable     = "todo_lists"
+	usersListsTable    = "users_lists"
+	todoItemsTable     = "todo_items"
+	listsItemsTable    = "lists_items"
+	refreshTokensTable = "refresh_tokens"
+	labelsTable        = "labels"
+	itemsLabelsTable   = "items_labels"
+	activityTable      = "activity"
 )
 
 type Config struct {
+	// Driver is one of DriverPostgres, DriverSQLite and DriverMemory
+	Driver   string
 	Host     string
 	Port     string
 	Username string
 	Password string
 	DBName   string
 	SSLMode  string
+	// Path is the database file of the SQLite driver
+	Path string
+	// AutoMigrate applies the embedded migrations when the storage is opened
+	AutoMigrate bool
+	// QueryTimeout bounds every repository call of the SQL drivers as a whole, not each of its statements, 0 means no limit
+	QueryTimeout time.Duration
 }
 
 func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"os"
+	"testing"
+)
+
+// TestPostgresConformance runs against the migrated database in TEST_DB_DSN, e.g.
+// "host=localhost port=5432 user=postgres password=qwerty dbname=postgres sslmode=disable".
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	defer db.Close()
+
+	testConformance(t, NewPostgresRepository(db, 0))
+}
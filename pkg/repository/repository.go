@@ -1,41 +1,157 @@
 package repository
 
 import (
+	"context"
+	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"time"
 	todolist_app "todolist-app"
+	"todolist-app/schema"
 )
 
 type Authorization interface {
-	CreateUser(user todolist_app.User) (int, error)
-	GetUser(username, password string) (todolist_app.User, error)
+	CreateUser(ctx context.Context, user todolist_app.User) (int, error)
+	GetUser(ctx context.Context, username string) (todolist_app.User, error)
+	UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error
+	CreateRefreshToken(ctx context.Context, token todolist_app.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (todolist_app.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, usedId int, next todolist_app.RefreshToken) error
+	RevokeSession(ctx context.Context, sessionId string) error
+	IsSessionActive(ctx context.Context, sessionId string) (bool, error)
 }
 
 type TodoList interface {
-	Create(userId int, list todolist_app.TodoList) (int, error)
-	GetAll(userId int) ([]todolist_app.TodoList, error)
-	GetById(userId, listId int) (todolist_app.TodoList, error)
-	Delete(userId, listId int) error
-	Update(userId, listId int, input todolist_app.UpdateListInput) error
+	Create(ctx context.Context, userId int, list todolist_app.TodoList) (int, error)
+	GetAll(ctx context.Context, userId int, filter todolist_app.ListFilter) ([]todolist_app.TodoList, string, error)
+	GetById(ctx context.Context, userId, listId int) (todolist_app.TodoList, error)
+	Delete(ctx context.Context, userId, listId int) error
+	Update(ctx context.Context, userId, listId int, input todolist_app.UpdateListInput) error
+	GetRole(ctx context.Context, userId, listId int) (todolist_app.Role, error)
+	GetMembers(ctx context.Context, listId int) ([]todolist_app.ListMember, error)
+	SaveMember(ctx context.Context, actorId, listId int, username string, role todolist_app.Role) (int, error)
+	DeleteMember(ctx context.Context, actorId, listId, userId int) error
 }
 
 type TodoItem interface {
-	Create(listId int, item todolist_app.TodoItem) (int, error)
-	GetAll(userId, listId int) ([]todolist_app.TodoItem, error)
-	GetById(userId, itemId int) (todolist_app.TodoItem, error)
-	Delete(userId, itemId int) error
-	Update(userId, itemId int, input todolist_app.UpdateItemInput) error
+	Create(ctx context.Context, userId, listId int, item todolist_app.TodoItem) (int, error)
+	GetAll(ctx context.Context, userId, listId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error)
+	GetAllByUser(ctx context.Context, userId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error)
+	GetById(ctx context.Context, userId, itemId int) (todolist_app.TodoItem, error)
+	GetSubtasks(ctx context.Context, userId, parentId int) ([]todolist_app.TodoItem, error)
+	Delete(ctx context.Context, userId, itemId int) error
+	Update(ctx context.Context, userId, itemId int, input todolist_app.UpdateItemInput) error
+	UpdateSeries(ctx context.Context, userId, itemId int, input todolist_app.UpdateSeriesInput) error
+	StopSeries(ctx context.Context, userId, itemId int) error
+	GetRole(ctx context.Context, userId, itemId int) (todolist_app.Role, error)
+	Reorder(ctx context.Context, userId, listId int, itemIds []int) error
+	SetPosition(ctx context.Context, userId, itemId int, input todolist_app.ItemPositionInput) error
+	Move(ctx context.Context, userId, itemId, listId int) error
+	Batch(ctx context.Context, userId, listId int, operations []todolist_app.BatchOperation, atomic bool) ([]todolist_app.BatchResult, error)
+	SetDone(ctx context.Context, userId, listId int, done bool) ([]todolist_app.TodoItem, error)
+	DeleteDone(ctx context.Context, userId, listId int) (int, error)
+}
+
+type Label interface {
+	Create(ctx context.Context, userId int, label todolist_app.Label) (int, error)
+	GetAll(ctx context.Context, userId int) ([]todolist_app.Label, error)
+	GetById(ctx context.Context, userId, labelId int) (todolist_app.Label, error)
+	Update(ctx context.Context, userId, labelId int, input todolist_app.UpdateLabelInput) error
+	Delete(ctx context.Context, userId, labelId int) error
+	GetByItem(ctx context.Context, userId, itemId int) ([]todolist_app.Label, error)
+	Attach(ctx context.Context, itemId, labelId int) error
+	Detach(ctx context.Context, userId, itemId, labelId int) error
+}
+
+type Search interface {
+	Search(ctx context.Context, userId int, input todolist_app.SearchInput) ([]todolist_app.SearchHit, error)
+}
+
+type Trash interface {
+	GetAll(ctx context.Context, userId int) ([]todolist_app.TrashEntry, error)
+	RestoreList(ctx context.Context, userId, listId int) error
+	RestoreItem(ctx context.Context, userId, itemId int) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Activity interface {
+	GetByList(ctx context.Context, listId int, page todolist_app.Page) ([]todolist_app.Activity, string, error)
+	GetByItem(ctx context.Context, userId, itemId int, page todolist_app.Page) ([]todolist_app.Activity, string, error)
 }
 
 type Repository struct {
 	Authorization
 	TodoItem
 	TodoList
+	Label
+	Search
+	Trash
+	Activity
 }
 
-func NewRepository(db *sqlx.DB) *Repository {
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// NewRepository opens the storage cfg.Driver selects, Postgres by default, and returns the repositories
+// on top of it together with the function closing the storage. With cfg.AutoMigrate the pending
+// migrations are applied first.
+func NewRepository(cfg Config) (*Repository, func() error, error) {
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		db, err := NewPostgresDB(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := autoMigrate(cfg, db, schema.Postgres); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		return NewPostgresRepository(db, cfg.QueryTimeout), db.Close, nil
+	case DriverSQLite:
+		db, err := NewSQLiteDB(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := autoMigrate(cfg, db, schema.SQLite); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		return NewSQLiteRepository(db, cfg.QueryTimeout), db.Close, nil
+	case DriverMemory:
+		logrus.Warn("using in-memory storage, data is lost on shutdown")
+
+		return NewMemoryRepository(NewMemoryDB()), func() error { return nil }, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
+
+func NewPostgresRepository(db *sqlx.DB, queryTimeout time.Duration) *Repository {
 	return &Repository{
-		Authorization: NewAuthPostgres(db),
-		TodoList:      NewTodoListPostgres(db),
-		TodoItem:      NewTodoItemPostgres(db),
+		Authorization: NewAuthPostgres(db, queryTimeout),
+		TodoList:      NewTodoListPostgres(db, queryTimeout),
+		TodoItem:      NewTodoItemPostgres(db, queryTimeout),
+		Label:         NewLabelPostgres(db, queryTimeout),
+		Search:        NewSearchPostgres(db, queryTimeout),
+		Trash:         NewTrashPostgres(db, queryTimeout),
+		Activity:      NewActivityPostgres(db, queryTimeout),
 	}
 }
+
+// withTimeout bounds a repository call by the query timeout. The timeout is per call, not per statement:
+// a transaction shares it among all its queries, so a batch of many operations has the same budget as
+// a single update. A zero timeout leaves the call bounded by ctx only.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
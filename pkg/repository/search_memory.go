@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	todolist_app "todolist-app"
+	"unicode"
+)
+
+type SearchMemory struct {
+	db *MemoryDB
+}
+
+func NewSearchMemory(db *MemoryDB) *SearchMemory {
+	return &SearchMemory{db: db}
+}
+
+// Search approximates the full-text search of Postgres: every word of the query has to start a word
+// of the title or description, words prefixed with - must not, and title matches rank higher.
+// There is no stemming beyond that prefix match and OR is ignored.
+func (r *SearchMemory) Search(ctx context.Context, userId int, input todolist_app.SearchInput) ([]todolist_app.SearchHit, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	terms, excluded := parseSearchQuery(input.Q)
+
+	hits := make([]todolist_app.SearchHit, 0)
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, list := range s.lists {
+			if _, ok := s.role(userId, list.Id); !ok {
+				continue
+			}
+
+			if hit, ok := searchHit(terms, excluded, list.Title, list.Description); ok {
+				hit.Kind, hit.Id, hit.ListId = todolist_app.SearchHitList, list.Id, list.Id
+				hits = append(hits, hit)
+			}
+		}
+
+		for _, item := range s.items {
+			if _, _, ok := s.visibleItem(userId, item.Id); !ok {
+				continue
+			}
+
+			if hit, ok := searchHit(terms, excluded, item.Title, item.Description); ok {
+				hit.Kind, hit.Id, hit.ListId = todolist_app.SearchHitItem, item.Id, item.ListId
+				hits = append(hits, hit)
+			}
+		}
+
+		return nil
+	})
+
+	return rankSearchHits(hits, limit), err
+}
+
+// rankSearchHits orders hits like the Postgres search does and keeps the first limit of them.
+func rankSearchHits(hits []todolist_app.SearchHit, limit int) []todolist_app.SearchHit {
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		switch {
+		case a.Rank != b.Rank:
+			return a.Rank > b.Rank
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		}
+		return a.Id < b.Id
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+func parseSearchQuery(q string) (terms, excluded []string) {
+	for _, field := range strings.Fields(strings.ToLower(q)) {
+		negated := strings.HasPrefix(field, "-")
+		for _, word := range searchWords(field) {
+			switch {
+			case word == "or":
+			case negated:
+				excluded = append(excluded, word)
+			default:
+				terms = append(terms, word)
+			}
+		}
+	}
+
+	return terms, excluded
+}
+
+func searchWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+}
+
+// searchHit matches a title and description, weighting title words like setweight 'A' and
+// description words like 'B' do.
+func searchHit(terms, excluded []string, title, description string) (todolist_app.SearchHit, bool) {
+	titleWords := searchWords(strings.ToLower(title))
+	descriptionWords := searchWords(strings.ToLower(description))
+
+	for _, term := range excluded {
+		if hasSearchWord(titleWords, term) || hasSearchWord(descriptionWords, term) {
+			return todolist_app.SearchHit{}, false
+		}
+	}
+
+	if len(terms) == 0 {
+		return todolist_app.SearchHit{}, false
+	}
+
+	var rank float64
+	for _, term := range terms {
+		inTitle, inDescription := hasSearchWord(titleWords, term), hasSearchWord(descriptionWords, term)
+		switch {
+		case inTitle:
+			rank += 1
+		case inDescription:
+			rank += 0.4
+		default:
+			return todolist_app.SearchHit{}, false
+		}
+	}
+
+	return todolist_app.SearchHit{
+		Title:   highlight(title, terms),
+		Snippet: highlight(description, terms),
+		Rank:    rank / float64(len(terms)),
+	}, true
+}
+
+func hasSearchWord(words []string, term string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlight escapes s for HTML and wraps the words matching a term in <mark> tags.
+func highlight(s string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsNumber(runes[i]) {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsNumber(runes[j])) {
+			j++
+		}
+
+		word := html.EscapeString(string(runes[i:j]))
+		if matchesAny(strings.ToLower(word), terms) {
+			b.WriteString("<mark>" + word + "</mark>")
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+
+	return b.String()
+}
+
+func matchesAny(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+
+	return false
+}
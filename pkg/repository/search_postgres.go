@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+	todolist_app "todolist-app"
+)
+
+const (
+	searchConfig = "english"
+
+	// highlight every match in titles, and show a few fragments of long descriptions
+	titleHeadlineOptions   = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	snippetHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5"
+)
+
+type SearchPostgres struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewSearchPostgres(db *sqlx.DB, timeout time.Duration) *SearchPostgres {
+	return &SearchPostgres{db: db, timeout: timeout}
+}
+
+// Search ranks the lists and items of the user matching a web search style query
+// (quoted phrases, OR and -word are supported) against the indexed search columns.
+func (r *SearchPostgres) Search(ctx context.Context, userId int, input todolist_app.SearchInput) ([]todolist_app.SearchHit, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	hits := make([]todolist_app.SearchHit, 0)
+	// the hits are ranked and limited before the headlines, which are costly, are built for the rows returned
+	query := fmt.Sprintf(`WITH query AS (SELECT websearch_to_tsquery('%[1]s', $2) AS q),
+								hits AS (SELECT '%[2]s' AS kind, tl.id, tl.id AS list_id, tl.title,
+											COALESCE(tl.description, '') AS description, ts_rank(tl.search, query.q) AS rank
+										FROM %[6]s tl INNER JOIN %[8]s ul on ul.list_id = tl.id, query
+										WHERE ul.user_id = $1 AND tl.deleted_at IS NULL AND tl.search @@ query.q
+										UNION ALL
+										SELECT '%[3]s' AS kind, ti.id, li.list_id, ti.title,
+											COALESCE(ti.description, '') AS description, ts_rank(ti.search, query.q) AS rank
+										FROM %[7]s ti INNER JOIN %[9]s li on li.item_id = ti.id
+											INNER JOIN %[8]s ul on ul.list_id = li.list_id, query
+										WHERE ul.user_id = $1 AND ti.deleted_at IS NULL AND ti.search @@ query.q
+										ORDER BY rank DESC, kind, id LIMIT $3)
+								SELECT hits.kind, hits.id, hits.list_id,
+									ts_headline('%[1]s', %[10]s, query.q, '%[4]s') AS title,
+									ts_headline('%[1]s', %[11]s, query.q, '%[5]s') AS snippet,
+									hits.rank
+								FROM hits, query
+								ORDER BY hits.rank DESC, hits.kind, hits.id`,
+		searchConfig, todolist_app.SearchHitList, todolist_app.SearchHitItem, titleHeadlineOptions,
+		snippetHeadlineOptions, todoListsTable, todoItemsTable, usersListsTable, listsItemsTable,
+		escapeHTML("hits.title"), escapeHTML("hits.description"))
+	err := r.db.SelectContext(ctx, &hits, query, userId, input.Q, limit)
+
+	return hits, err
+}
+
+// escapeHTML returns the SQL escaping the text of expr like html.EscapeString does. ts_headline keeps
+// the entities as they are, so its <mark> tags are the only markup in the headline.
+func escapeHTML(expr string) string {
+	for _, r := range []struct{ from, to string }{
+		{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&#34;"}, {"''", "&#39;"},
+	} {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, r.from, r.to)
+	}
+
+	return expr
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type SearchSQLite struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewSearchSQLite(db *sqlx.DB, timeout time.Duration) *SearchSQLite {
+	return &SearchSQLite{db: db, timeout: timeout}
+}
+
+// Search matches the query like SearchMemory does, as SQLite has no full-text search built in:
+// LIKE picks the lists and items containing every word and the matches are ranked here. LIKE only ignores
+// the case of ASCII letters, so other letters have to match in case.
+func (r *SearchSQLite) Search(ctx context.Context, userId int, input todolist_app.SearchInput) ([]todolist_app.SearchHit, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	hits := make([]todolist_app.SearchHit, 0)
+
+	terms, excluded := parseSearchQuery(input.Q)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	// ?1 is the user, the terms follow
+	args := []interface{}{userId}
+	for _, term := range terms {
+		args = append(args, likePattern(term))
+	}
+
+	containsTerms := func(alias string) string {
+		conditions := make([]string, len(terms))
+		for i := range terms {
+			conditions[i] = fmt.Sprintf(`(%[1]s.title LIKE ?%[2]d ESCAPE '\' OR %[1]s.description LIKE ?%[2]d ESCAPE '\')`,
+				alias, i+2)
+		}
+		return strings.Join(conditions, " AND ")
+	}
+
+	var rows []struct {
+		Kind        string `db:"kind"`
+		Id          int    `db:"id"`
+		ListId      int    `db:"list_id"`
+		Title       string `db:"title"`
+		Description string `db:"description"`
+	}
+	query := fmt.Sprintf(`SELECT '%[1]s' AS kind, tl.id, tl.id AS list_id, tl.title, COALESCE(tl.description, '') AS description
+								FROM %[3]s tl INNER JOIN %[5]s ul on ul.list_id = tl.id
+								WHERE ul.user_id = ?1 AND tl.deleted_at IS NULL AND %[7]s
+								UNION ALL
+								SELECT '%[2]s' AS kind, ti.id, li.list_id, ti.title, COALESCE(ti.description, '') AS description
+								FROM %[4]s ti INNER JOIN %[6]s li on li.item_id = ti.id
+									INNER JOIN %[5]s ul on ul.list_id = li.list_id
+								WHERE ul.user_id = ?1 AND ti.deleted_at IS NULL AND %[8]s`,
+		todolist_app.SearchHitList, todolist_app.SearchHitItem, todoListsTable, todoItemsTable, usersListsTable,
+		listsItemsTable, containsTerms("tl"), containsTerms("ti"))
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if hit, ok := searchHit(terms, excluded, row.Title, row.Description); ok {
+			hit.Kind, hit.Id, hit.ListId = row.Kind, row.Id, row.ListId
+			hits = append(hits, hit)
+		}
+	}
+
+	return rankSearchHits(hits, limit), nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+	todolist_app "todolist-app"
+)
+
+// NewSQLiteDB opens the database file at cfg.Path, which has to be migrated with schema/sqlite.
+// Transactions take the write lock when they begin, which stands in for the row locks the Postgres
+// repositories take with FOR UPDATE, and a single connection keeps writers from failing as busy.
+func NewSQLiteDB(cfg Config) (*sqlx.DB, error) {
+	db, err := sqlx.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_txlock=immediate&_busy_timeout=5000",
+		cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func NewSQLiteRepository(db *sqlx.DB, queryTimeout time.Duration) *Repository {
+	return &Repository{
+		Authorization: NewAuthSQLite(db, queryTimeout),
+		TodoList:      NewTodoListSQLite(db, queryTimeout),
+		TodoItem:      NewTodoItemSQLite(db, queryTimeout),
+		Label:         NewLabelSQLite(db, queryTimeout),
+		Search:        NewSearchSQLite(db, queryTimeout),
+		Trash:         NewTrashSQLite(db, queryTimeout),
+		Activity:      NewActivitySQLite(db, queryTimeout),
+	}
+}
+
+// sqliteNow returns the time a write stores in the timestamps it changes, like now() in a transaction.
+// SQLite keeps timestamps as text, which only sorts like the times it represents when every time is
+// in the same zone, so the SQLite repositories bind all times in UTC. Microseconds match timestamptz.
+func sqliteNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// sqliteTime returns t the way sqliteNow returns the current time.
+func sqliteTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	utc := t.UTC().Truncate(time.Microsecond)
+
+	return &utc
+}
+
+// inQuery expands the slice arguments of query into lists of placeholders, for the = ANY($1)
+// conditions of the Postgres queries.
+func inQuery(query string, args ...interface{}) (string, []interface{}, error) {
+	return sqlx.In(query, args...)
+}
+
+func recordSQLiteActivity(ctx context.Context, tx *sqlx.Tx, now time.Time, entry activityEntry) error {
+	changes, err := sqliteChanges(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (list_id, item_id, actor_id, entity, action, changes, created_at)
+								VALUES (?, ?, ?, ?, ?, ?, ?)`, activityTable)
+	_, err = tx.ExecContext(ctx, query, entry.ListId, entry.ItemId, entry.ActorId, entry.Entity, entry.Action, changes, now)
+
+	return err
+}
+
+// recordSQLiteItemsActivity records the same entry for several items, each in its current list.
+func recordSQLiteItemsActivity(ctx context.Context, tx *sqlx.Tx, now time.Time, itemIds []int, entry activityEntry) error {
+	changes, err := sqliteChanges(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := inQuery(fmt.Sprintf(`INSERT INTO %s (list_id, item_id, actor_id, entity, action, changes, created_at)
+								SELECT li.list_id, li.item_id, ?, ?, ?, ?, ? FROM %s li WHERE li.item_id IN (?)`,
+		activityTable, listsItemsTable), entry.ActorId, entry.Entity, entry.Action, changes, now, itemIds)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// sqliteChanges stores changes as text rather than a blob, so the JSON functions of SQLite can read them.
+func sqliteChanges(changes todolist_app.Changes) (string, error) {
+	value, err := changes.Value()
+	if err != nil {
+		return "", err
+	}
+
+	return string(value.([]byte)), nil
+}
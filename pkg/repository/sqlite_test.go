@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"github.com/jmoiron/sqlx"
+	"testing"
+	"todolist-app/schema"
+)
+
+func TestSQLiteConformance(t *testing.T) {
+	db := openSQLite(t)
+	migrateSQLite(t, db, "up")
+
+	testConformance(t, NewSQLiteRepository(db, 0))
+}
+
+// TestSQLiteMigrations checks that every migration can be undone and applied again,
+// and that the Migrator tracks the version on the way.
+func TestSQLiteMigrations(t *testing.T) {
+	db := openSQLite(t)
+	migrateSQLite(t, db, "up")
+	migrateSQLite(t, db, "down")
+	migrateSQLite(t, db, "up")
+
+	m, err := NewMigrator(db, schema.SQLite)
+	if err != nil {
+		t.Fatalf("reading the migrations: %v", err)
+	}
+
+	if applied, err := m.Up(); err != nil || applied != 0 {
+		t.Fatalf("Up on a migrated database applied %d migrations: %v", applied, err)
+	}
+
+	status, err := m.Status()
+	last := m.migrations[len(m.migrations)-1].Version
+	if err != nil || status.Version != last || status.Dirty {
+		t.Fatalf("Status = %d, dirty %v, want %d: %v", status.Version, status.Dirty, last, err)
+	}
+
+	// a schema without a version, like one applied by hand, is left alone until a version is forced
+	if err := m.Force(0); err != nil {
+		t.Fatalf("Force(0): %v", err)
+	}
+
+	if applied, err := m.Up(); err == nil || applied != 0 {
+		t.Fatalf("Up on a schema without a version applied %d migrations: %v", applied, err)
+	}
+}
+
+func openSQLite(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := NewSQLiteDB(Config{Path: ":memory:"})
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func migrateSQLite(t *testing.T, db *sqlx.DB, direction string) {
+	t.Helper()
+
+	m, err := NewMigrator(db, schema.SQLite)
+	if err != nil {
+		t.Fatalf("reading the migrations: %v", err)
+	}
+
+	if direction == "up" {
+		_, err = m.Up()
+	} else {
+		_, err = m.Down(len(m.migrations))
+	}
+	if err != nil {
+		t.Fatalf("migrating %s: %v", direction, err)
+	}
+}
@@ -0,0 +1,841 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type TodoItemMemory struct {
+	db *MemoryDB
+}
+
+func NewTodoItemMemory(db *MemoryDB) *TodoItemMemory {
+	return &TodoItemMemory{db: db}
+}
+
+func (r *TodoItemMemory) Create(ctx context.Context, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	var itemId int
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		var err error
+		itemId, err = r.create(tx, userId, listId, item)
+		return err
+	})
+
+	return itemId, err
+}
+
+func (r *TodoItemMemory) create(tx *memoryState, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	if err := checkLength("item", 255, item.Title, item.Description); err != nil {
+		return 0, err
+	}
+
+	if item.Recurrence != nil {
+		if err := checkLength("item", 255, *item.Recurrence); err != nil {
+			return 0, err
+		}
+	}
+
+	if !item.Priority.Valid() {
+		return 0, todolist_app.NewValidationError("invalid item")
+	}
+
+	if _, ok := tx.lists[listId]; !ok {
+		return 0, todolist_app.NewNotFoundError("referenced record not found")
+	}
+
+	if item.ParentId != nil {
+		if _, ok := tx.items[*item.ParentId]; !ok {
+			return 0, todolist_app.NewNotFoundError("referenced record not found")
+		}
+	}
+
+	itemId := tx.nextId()
+
+	// the first item of a series gives the series its id
+	seriesId := item.SeriesId
+	if item.Recurrence != nil && seriesId == nil {
+		seriesId = &itemId
+	}
+
+	if seriesId != nil && item.DueAt != nil {
+		for _, other := range tx.items {
+			if other.SeriesId != nil && *other.SeriesId == *seriesId && other.DueAt != nil && other.DueAt.Equal(*item.DueAt) {
+				return 0, todolist_app.NewConflictError("item already exists")
+			}
+		}
+	}
+
+	var last float64
+	for _, other := range tx.items {
+		if other.ListId == listId && other.Position > last {
+			last = other.Position
+		}
+	}
+
+	tx.items[itemId] = memoryItem{TodoItem: todolist_app.TodoItem{
+		Id:          itemId,
+		ListId:      listId,
+		ParentId:    item.ParentId,
+		Title:       item.Title,
+		Description: item.Description,
+		DueAt:       item.DueAt,
+		RemindAt:    item.RemindAt,
+		Position:    last + positionGap,
+		CreatedAt:   tx.now,
+		UpdatedAt:   tx.now,
+		Priority:    item.Priority,
+		Recurrence:  item.Recurrence,
+		SeriesId:    seriesId,
+		Version:     1,
+	}}
+
+	if item.ParentId != nil {
+		tx.touchItems([]int{*item.ParentId})
+	}
+
+	tx.recordActivity(activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionCreated,
+		Changes: todolist_app.Changes{
+			"title":       {After: item.Title},
+			"description": {After: item.Description},
+			"parent_id":   {After: item.ParentId},
+		},
+	})
+
+	return itemId, nil
+}
+
+func (r *TodoItemMemory) GetAll(ctx context.Context, userId, listId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	return r.getPage(ctx, func(item memoryItem) bool {
+		return item.ListId == listId && item.ParentId == nil
+	}, userId, filter, "position")
+}
+
+func (r *TodoItemMemory) GetAllByUser(ctx context.Context, userId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	return r.getPage(ctx, func(item memoryItem) bool { return true }, userId, filter, "due_at")
+}
+
+func (r *TodoItemMemory) getPage(ctx context.Context, match func(item memoryItem) bool, userId int,
+	filter todolist_app.ItemFilter, defaultSort string) ([]todolist_app.TodoItem, string, error) {
+	page, err := newKeyset(itemSortColumns, filter.Page, defaultSort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []todolist_app.TodoItem
+	err = r.db.read(ctx, func(s *memoryState) error {
+		for _, item := range s.items {
+			if _, _, ok := s.visibleItem(userId, item.Id); !ok || !match(item) || !matchesFilter(s, userId, item, filter) {
+				continue
+			}
+
+			items = append(items, s.itemView(item))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, next := page.page(items, func(i todolist_app.TodoItem) int { return i.Id })
+
+	return items, next, nil
+}
+
+// matchesFilter applies the conditions getPage of the Postgres repository builds from the filter.
+func matchesFilter(s *memoryState, userId int, item memoryItem, filter todolist_app.ItemFilter) bool {
+	if q := strings.ToLower(filter.Q); q != "" && !strings.Contains(strings.ToLower(item.Title), q) &&
+		!strings.Contains(strings.ToLower(item.Description), q) {
+		return false
+	}
+
+	if filter.Done != nil && item.Done != *filter.Done {
+		return false
+	}
+
+	if filter.DueBefore != nil && (item.DueAt == nil || !item.DueAt.Before(*filter.DueBefore)) {
+		return false
+	}
+
+	if filter.DueAfter != nil && (item.DueAt == nil || !item.DueAt.After(*filter.DueAfter)) {
+		return false
+	}
+
+	if filter.Overdue && (item.DueAt == nil || !item.DueAt.Before(time.Now()) || item.Done) {
+		return false
+	}
+
+	if filter.MinPriority != nil && item.Priority < *filter.MinPriority {
+		return false
+	}
+
+	if filter.Label != nil {
+		// labels are personal, so only the requesting user's labels match
+		label, ok := s.labels[*filter.Label]
+		if !ok || label.UserId != userId || !s.itemsLabels[memoryItemLabel{ItemId: item.Id, LabelId: label.Id}] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *TodoItemMemory) GetById(ctx context.Context, userId, itemId int) (todolist_app.TodoItem, error) {
+	var item todolist_app.TodoItem
+	err := r.db.read(ctx, func(s *memoryState) error {
+		found, _, ok := s.visibleItem(userId, itemId)
+		if !ok {
+			return notFound("item")
+		}
+
+		item = s.itemView(found)
+
+		return nil
+	})
+
+	return item, err
+}
+
+func (r *TodoItemMemory) GetSubtasks(ctx context.Context, userId, parentId int) ([]todolist_app.TodoItem, error) {
+	var items []todolist_app.TodoItem
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, item := range s.items {
+			if item.ParentId == nil || *item.ParentId != parentId {
+				continue
+			}
+
+			if _, _, ok := s.visibleItem(userId, item.Id); ok {
+				items = append(items, s.itemView(item))
+			}
+		}
+
+		return nil
+	})
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Position != items[j].Position {
+			return items[i].Position < items[j].Position
+		}
+		return items[i].Id < items[j].Id
+	})
+
+	return items, err
+}
+
+func (r *TodoItemMemory) GetRole(ctx context.Context, userId, itemId int) (todolist_app.Role, error) {
+	var role todolist_app.Role
+	err := r.db.read(ctx, func(s *memoryState) error {
+		var ok bool
+		if _, role, ok = s.visibleItem(userId, itemId); !ok {
+			return notFound("item")
+		}
+
+		return nil
+	})
+
+	return role, err
+}
+
+// Delete moves the item and its subtasks to the trash. They share the deletion time,
+// which is how RestoreItem knows which subtasks to bring back.
+func (r *TodoItemMemory) Delete(ctx context.Context, userId, itemId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		return r.delete(tx, userId, itemId)
+	})
+}
+
+func (r *TodoItemMemory) delete(tx *memoryState, userId, itemId int) error {
+	item, _, ok := tx.visibleItem(userId, itemId)
+	if !ok {
+		return notFound("item")
+	}
+
+	tx.trashItems([]int{itemId})
+	tx.touchParents([]int{itemId})
+
+	tx.recordActivity(activityEntry{
+		ListId:  item.ListId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionDeleted,
+	})
+
+	return nil
+}
+
+// trashItems deletes the items and their subtasks that are not deleted yet.
+func (s *memoryState) trashItems(itemIds []int) {
+	trashed := make(map[int]bool, len(itemIds))
+	for _, id := range itemIds {
+		trashed[id] = true
+	}
+
+	now := s.now
+	for id, item := range s.items {
+		if item.DeletedAt == nil && (trashed[id] || item.ParentId != nil && trashed[*item.ParentId]) {
+			item.DeletedAt = &now
+			s.items[id] = item
+		}
+	}
+}
+
+func (r *TodoItemMemory) Update(ctx context.Context, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		return r.update(tx, userId, itemId, input)
+	})
+}
+
+func (r *TodoItemMemory) update(tx *memoryState, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	item, _, ok := tx.visibleItem(userId, itemId)
+	if !ok {
+		return notFound("item")
+	}
+
+	if input.Version != nil && *input.Version != item.Version {
+		return errItemModified
+	}
+
+	// the reminder is checked against the stored due time as well, not only one sent along
+	dueAt, remindAt, err := input.Schedule(item.DueAt, item.RemindAt)
+	if err != nil {
+		return err
+	}
+
+	before := item.TodoItem
+	changes := make(todolist_app.Changes)
+	addChange(changes, "title", item.Title, input.Title)
+	addChange(changes, "description", item.Description, input.Description)
+	addChange(changes, "done", item.Done, input.Done)
+	addTimeChange(changes, "due_at", item.DueAt, dueAt)
+	addTimeChange(changes, "remind_at", item.RemindAt, remindAt)
+	addChange(changes, "priority", item.Priority, input.Priority)
+
+	if input.Title != nil {
+		item.Title = *input.Title
+	}
+	if input.Description != nil {
+		item.Description = *input.Description
+	}
+	if input.Done != nil {
+		item.setDone(*input.Done, tx)
+	}
+	item.DueAt, item.RemindAt = dueAt, remindAt
+	if input.Priority != nil {
+		item.Priority = *input.Priority
+	}
+
+	if err := checkLength("item", 255, item.Title, item.Description); err != nil {
+		return err
+	}
+
+	if !item.Priority.Valid() {
+		return todolist_app.NewValidationError("invalid item")
+	}
+
+	item.Version++
+	item.UpdatedAt = tx.now
+	tx.items[itemId] = item
+	tx.touchParents([]int{itemId})
+
+	if len(changes) > 0 {
+		tx.recordActivity(activityEntry{
+			ListId:  item.ListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionUpdated,
+			Changes: changes,
+		})
+	}
+
+	if input.Done != nil && *input.Done && !before.Done && before.Recurrence != nil {
+		return r.createNextOccurrence(tx, userId, before, input)
+	}
+
+	return nil
+}
+
+// createNextOccurrence spawns the undone item that follows a recurring item input completes, in the same write.
+func (r *TodoItemMemory) createNextOccurrence(tx *memoryState, userId int, item todolist_app.TodoItem, input todolist_app.UpdateItemInput) error {
+	next, ok, err := todolist_app.NextOccurrence(item, input, tx.now)
+	if err != nil || !ok {
+		return err
+	}
+
+	_, err = r.create(tx, userId, item.ListId, next)
+	if todolist_app.ErrorCodeOf(err) == todolist_app.CodeConflict {
+		// the occurrence was already spawned by an earlier completion
+		return nil
+	}
+
+	return err
+}
+
+// setDone changes the done flag. Completing an already done item keeps its completion time.
+func (i *memoryItem) setDone(done bool, tx *memoryState) {
+	i.Done = done
+	switch {
+	case !done:
+		i.CompletedAt = nil
+	case i.CompletedAt == nil:
+		now := tx.now
+		i.CompletedAt = &now
+	}
+}
+
+// seriesItems returns the ids of the items of the item's series that the user can write to
+// and that match. The series is found only when the user can see the item.
+func (s *memoryState) seriesItems(userId, itemId int, match func(item memoryItem) bool) (int, []int) {
+	item, _, ok := s.visibleItem(userId, itemId)
+	if !ok {
+		return 0, nil
+	}
+
+	seriesId := item.Id
+	if item.SeriesId != nil {
+		seriesId = *item.SeriesId
+	}
+
+	var itemIds []int
+	for id, other := range s.items {
+		inSeries := other.Id == seriesId || other.SeriesId != nil && *other.SeriesId == seriesId
+		role := s.members[memoryMember{ListId: other.ListId, UserId: userId}]
+		if inSeries && role.CanWrite() && match(other) {
+			itemIds = append(itemIds, id)
+		}
+	}
+	sort.Ints(itemIds)
+
+	return seriesId, itemIds
+}
+
+// UpdateSeries applies the input to the undone items of the item's series.
+func (r *TodoItemMemory) UpdateSeries(ctx context.Context, userId, itemId int, input todolist_app.UpdateSeriesInput) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		seriesId, itemIds := tx.seriesItems(userId, itemId, func(item memoryItem) bool {
+			return !item.Done && item.DeletedAt == nil
+		})
+		if len(itemIds) == 0 {
+			return todolist_app.NewNotFoundError("series not found")
+		}
+
+		changes := make(todolist_app.Changes)
+		if input.Title != nil {
+			changes["title"] = todolist_app.Change{After: *input.Title}
+		}
+		if input.Description != nil {
+			changes["description"] = todolist_app.Change{After: *input.Description}
+		}
+		if input.Priority != nil {
+			changes["priority"] = todolist_app.Change{After: *input.Priority}
+		}
+		if input.Recurrence != nil {
+			changes["recurrence"] = todolist_app.Change{After: *input.Recurrence}
+		}
+
+		for _, id := range itemIds {
+			item := tx.items[id]
+			if input.Title != nil {
+				item.Title = *input.Title
+			}
+			if input.Description != nil {
+				item.Description = *input.Description
+			}
+			if input.Priority != nil {
+				item.Priority = *input.Priority
+			}
+			if input.Recurrence != nil {
+				recurrence, series := *input.Recurrence, seriesId
+				item.Recurrence = &recurrence
+				item.SeriesId = &series
+			}
+
+			if err := checkLength("item", 255, item.Title, item.Description); err != nil {
+				return err
+			}
+
+			item.Version++
+			item.UpdatedAt = tx.now
+			tx.items[id] = item
+		}
+
+		tx.recordItemsActivity(itemIds, activityEntry{
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionSeriesUpdated,
+			Changes: changes,
+		})
+
+		return nil
+	})
+}
+
+// StopSeries ends the item's series. Its items are kept, but completing them no longer spawns new ones.
+func (r *TodoItemMemory) StopSeries(ctx context.Context, userId, itemId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		_, itemIds := tx.seriesItems(userId, itemId, func(item memoryItem) bool {
+			return item.Recurrence != nil
+		})
+		if len(itemIds) == 0 {
+			return todolist_app.NewNotFoundError("series not found")
+		}
+
+		for _, id := range itemIds {
+			item := tx.items[id]
+			item.Recurrence = nil
+			item.Version++
+			item.UpdatedAt = tx.now
+			tx.items[id] = item
+		}
+
+		tx.recordItemsActivity(itemIds, activityEntry{
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionSeriesStopped,
+			Changes: todolist_app.Changes{"recurrence": {After: nil}},
+		})
+
+		return nil
+	})
+}
+
+func (r *TodoItemMemory) Reorder(ctx context.Context, userId, listId int, itemIds []int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		changed, err := orderItems(tx.positions(listId), itemIds)
+		if err != nil {
+			return err
+		}
+
+		tx.savePositions(changed)
+
+		tx.recordActivity(activityEntry{
+			ListId:  listId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityList,
+			Action:  todolist_app.ActionReordered,
+			Changes: todolist_app.Changes{"item_ids": {After: itemIds}},
+		})
+
+		return nil
+	})
+}
+
+func (r *TodoItemMemory) SetPosition(ctx context.Context, userId, itemId int, input todolist_app.ItemPositionInput) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		item, ok := tx.items[itemId]
+		if !ok || item.DeletedAt != nil {
+			return notFound("item")
+		}
+
+		changed, err := placeItem(tx.positions(item.ListId), itemId, input)
+		if err != nil {
+			return err
+		}
+
+		tx.savePositions(changed)
+
+		anchorId, before := input.Anchor()
+		anchor := "after_id"
+		if before {
+			anchor = "before_id"
+		}
+
+		tx.recordActivity(activityEntry{
+			ListId:  item.ListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionRepositioned,
+			Changes: todolist_app.Changes{anchor: {After: anchorId}},
+		})
+
+		return nil
+	})
+}
+
+// positions returns the positions of the items of the list that are not deleted, in order.
+func (s *memoryState) positions(listId int) []itemPosition {
+	var positions []itemPosition
+	for _, item := range s.items {
+		if item.ListId == listId && item.DeletedAt == nil {
+			positions = append(positions, itemPosition{ItemId: item.Id, Position: item.Position})
+		}
+	}
+
+	sortPositions(positions)
+
+	return positions
+}
+
+func sortPositions(positions []itemPosition) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Position != positions[j].Position {
+			return positions[i].Position < positions[j].Position
+		}
+		return positions[i].ItemId < positions[j].ItemId
+	})
+}
+
+// savePositions stores the positions and bumps the version of the items they move.
+func (s *memoryState) savePositions(positions []itemPosition) {
+	itemIds := make([]int, len(positions))
+	for i, p := range positions {
+		item := s.items[p.ItemId]
+		item.Position = p.Position
+		s.items[p.ItemId] = item
+		itemIds[i] = p.ItemId
+	}
+
+	s.touchItems(itemIds)
+}
+
+// touchItems bumps the version of items changed outside their own fields, like their position, so an update
+// made with the version read before is refused. Labels are private to their owner and leave the version alone.
+func (s *memoryState) touchItems(itemIds []int) {
+	for _, id := range itemIds {
+		if item, ok := s.items[id]; ok {
+			item.Version++
+			item.UpdatedAt = s.now
+			s.items[id] = item
+		}
+	}
+}
+
+// touchParents bumps the version of the parents of itemIds, since a parent shows its subtasks.
+func (s *memoryState) touchParents(itemIds []int) {
+	var parentIds []int
+	for _, id := range itemIds {
+		if item, ok := s.items[id]; ok && item.ParentId != nil {
+			parentIds = append(parentIds, *item.ParentId)
+		}
+	}
+
+	s.touchItems(parentIds)
+}
+
+// Move relinks the item and its subtasks to another list and appends them there. The user needs
+// write access to both lists.
+func (r *TodoItemMemory) Move(ctx context.Context, userId, itemId, listId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		source, sourceRole, ok := tx.visibleItem(userId, itemId)
+		if !ok {
+			return notFound("item")
+		}
+
+		targetRole, ok := tx.role(userId, listId)
+		if !ok {
+			return notFound("list")
+		}
+
+		if !sourceRole.CanWrite() || !targetRole.CanWrite() {
+			return todolist_app.NewForbiddenError("moving the item requires write access to both lists")
+		}
+
+		if source.ParentId != nil {
+			return todolist_app.NewValidationError("subtasks are moved together with their parent item")
+		}
+
+		if source.ListId == listId {
+			return nil
+		}
+
+		// trashed subtasks move too, so they are restored next to their parent
+		var moved []itemPosition
+		var last float64
+		for _, item := range tx.items {
+			if item.Id == itemId || item.ParentId != nil && *item.ParentId == itemId {
+				moved = append(moved, itemPosition{ItemId: item.Id, Position: item.Position})
+			}
+			if item.ListId == listId && item.Position > last {
+				last = item.Position
+			}
+		}
+		sortPositions(moved)
+
+		for i, p := range moved {
+			item := tx.items[p.ItemId]
+			item.ListId = listId
+			item.Position = last + float64(i+1)*positionGap
+			item.Version++
+			item.UpdatedAt = tx.now
+			tx.items[p.ItemId] = item
+		}
+
+		// both lists show the move in their activity
+		for _, activityListId := range []int{source.ListId, listId} {
+			tx.recordActivity(activityEntry{
+				ListId:  activityListId,
+				ItemId:  &itemId,
+				ActorId: userId,
+				Entity:  todolist_app.ActivityEntityItem,
+				Action:  todolist_app.ActionMoved,
+				Changes: todolist_app.Changes{"list_id": {Before: source.ListId, After: listId}},
+			})
+		}
+
+		return nil
+	})
+}
+
+// Batch runs the operations on the items of a list as one write. In atomic mode the first failing
+// operation discards the batch and its error is returned. Otherwise each operation runs on its own copy
+// of the data, so a failed one is undone on its own and reported in its result.
+func (r *TodoItemMemory) Batch(ctx context.Context, userId, listId int, operations []todolist_app.BatchOperation,
+	atomic bool) ([]todolist_app.BatchResult, error) {
+	results := make([]todolist_app.BatchResult, 0, len(operations))
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		for n, op := range operations {
+			result := todolist_app.BatchResult{Index: n, Op: op.Op}
+
+			opTx := tx
+			if !atomic {
+				opTx = tx.clone()
+				opTx.now = tx.now
+			}
+
+			id, err := r.runOperation(opTx, userId, listId, op)
+
+			var e *todolist_app.Error
+			switch {
+			case err == nil:
+				result.Id = id
+				*tx = *opTx
+			case !errors.As(err, &e):
+				return err
+			case atomic:
+				return &todolist_app.Error{Code: e.Code, Message: fmt.Sprintf("operation %d: %s", n, e.Message), Err: err}
+			default:
+				result.Id = id
+				result.Error = &todolist_app.BatchError{Code: e.Code, Message: e.Message}
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *TodoItemMemory) runOperation(tx *memoryState, userId, listId int, op todolist_app.BatchOperation) (int, error) {
+	if op.Op == todolist_app.BatchOpCreate {
+		return r.create(tx, userId, listId, *op.Item)
+	}
+
+	// updates and deletes are limited to the items of the batch's list
+	if item, ok := tx.items[*op.Id]; !ok || item.ListId != listId || item.DeletedAt != nil {
+		return *op.Id, todolist_app.NewNotFoundError("item not found")
+	}
+
+	if op.Op == todolist_app.BatchOpUpdate {
+		return *op.Id, r.update(tx, userId, *op.Id, *op.Input)
+	}
+
+	return *op.Id, r.delete(tx, userId, *op.Id)
+}
+
+// SetDone marks every item of the list, subtasks included, as done or not done. It returns
+// the items that changed as they were before.
+func (r *TodoItemMemory) SetDone(ctx context.Context, userId, listId int, done bool) ([]todolist_app.TodoItem, error) {
+	var items []todolist_app.TodoItem
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		for _, item := range tx.items {
+			if _, _, ok := tx.visibleItem(userId, item.Id); ok && item.ListId == listId && item.Done != done {
+				items = append(items, tx.itemView(item))
+			}
+		}
+
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Position != items[j].Position {
+				return items[i].Position < items[j].Position
+			}
+			return items[i].Id < items[j].Id
+		})
+
+		itemIds := make([]int, len(items))
+		for i, view := range items {
+			itemIds[i] = view.Id
+
+			item := tx.items[view.Id]
+			item.setDone(done, tx)
+			item.Version++
+			item.UpdatedAt = tx.now
+			tx.items[view.Id] = item
+		}
+
+		tx.touchParents(itemIds)
+
+		if len(itemIds) > 0 {
+			tx.recordItemsActivity(itemIds, activityEntry{
+				ActorId: userId,
+				Entity:  todolist_app.ActivityEntityItem,
+				Action:  todolist_app.ActionUpdated,
+				Changes: todolist_app.Changes{"done": {Before: !done, After: done}},
+			})
+		}
+
+		for _, item := range items {
+			if done && item.Recurrence != nil {
+				if err := r.createNextOccurrence(tx, userId, item, todolist_app.UpdateItemInput{}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// DeleteDone moves the done items of the list to the trash together with their subtasks
+// and returns how many done items were removed.
+func (r *TodoItemMemory) DeleteDone(ctx context.Context, userId, listId int) (int, error) {
+	var itemIds []int
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		for _, item := range tx.items {
+			if _, _, ok := tx.visibleItem(userId, item.Id); ok && item.ListId == listId && item.Done {
+				itemIds = append(itemIds, item.Id)
+			}
+		}
+
+		if len(itemIds) == 0 {
+			return nil
+		}
+		sort.Ints(itemIds)
+
+		tx.trashItems(itemIds)
+		tx.touchParents(itemIds)
+
+		tx.recordItemsActivity(itemIds, activityEntry{
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionDeleted,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(itemIds), nil
+}
@@ -1,108 +1,957 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"strings"
+	"time"
 	todolist_app "todolist-app"
 )
 
+const todoItemColumns = `ti.id, li.list_id, ti.parent_id, ti.title, ti.description, ti.done, ti.due_at, ti.remind_at,
+	ti.priority, ti.recurrence, ti.series_id, ti.version, li.position, ti.created_at,
+	ti.updated_at, ti.completed_at,
+	(SELECT count(*) FROM todo_items sub WHERE sub.parent_id = ti.id AND sub.deleted_at IS NULL AND sub.done) AS "progress.done",
+	(SELECT count(*) FROM todo_items sub WHERE sub.parent_id = ti.id AND sub.deleted_at IS NULL) AS "progress.total"`
+
 type TodoItemPostgres struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration
 }
 
-func NewTodoItemPostgres(db *sqlx.DB) *TodoItemPostgres {
-	return &TodoItemPostgres{db: db}
+func NewTodoItemPostgres(db *sqlx.DB, timeout time.Duration) *TodoItemPostgres {
+	return &TodoItemPostgres{db: db, timeout: timeout}
 }
 
-func (r *TodoItemPostgres) Create(listId int, item todolist_app.TodoItem) (int, error) {
-	tx, err := r.db.Begin()
+func (r *TodoItemPostgres) Create(ctx context.Context, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 
-	var itemId int
-	createItemQuery := fmt.Sprintf("INSERT INTO %s (title, description) values ($1, $2) RETURNING id", todoItemsTable)
-
-	row := tx.QueryRow(createItemQuery, item.Title, item.Description)
-	err = row.Scan(&itemId)
+	itemId, err := r.create(ctx, tx, userId, listId, item)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 
-	createListItemsQuery := fmt.Sprintf("INSERT INTO %s (list_id, item_id) values ($1, $2)", listsItemsTable)
-	_, err = tx.Exec(createListItemsQuery, listId, itemId)
-	if err != nil {
-		tx.Rollback()
+	return itemId, tx.Commit()
+}
+
+func (r *TodoItemPostgres) create(ctx context.Context, tx *sqlx.Tx, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	var itemId int
+	createItemQuery := fmt.Sprintf(`INSERT INTO %s (parent_id, title, description, due_at, remind_at, priority, recurrence, series_id)
+									values ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`, todoItemsTable)
+
+	row := tx.QueryRowContext(ctx, createItemQuery, item.ParentId, item.Title, item.Description, item.DueAt, item.RemindAt,
+		item.Priority, item.Recurrence, item.SeriesId)
+	if err := row.Scan(&itemId); err != nil {
+		return 0, translateError(err, "item")
+	}
+
+	// the first item of a series gives the series its id
+	if item.Recurrence != nil && item.SeriesId == nil {
+		startSeriesQuery := fmt.Sprintf("UPDATE %s SET series_id = id WHERE id = $1", todoItemsTable)
+		if _, err := tx.ExecContext(ctx, startSeriesQuery, itemId); err != nil {
+			return 0, err
+		}
+	}
+
+	createListItemsQuery := fmt.Sprintf(`INSERT INTO %s (list_id, item_id, position)
+									SELECT $1, $2, COALESCE(MAX(position), 0) + %d FROM %s WHERE list_id = $1`,
+		listsItemsTable, positionGap, listsItemsTable)
+	if _, err := tx.ExecContext(ctx, createListItemsQuery, listId, itemId); err != nil {
 		return 0, err
 	}
 
-	return itemId, tx.Commit()
+	if item.ParentId != nil {
+		if err := touchItems(ctx, tx, []int{*item.ParentId}); err != nil {
+			return 0, err
+		}
+	}
+
+	err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionCreated,
+		Changes: todolist_app.Changes{
+			"title":       {After: item.Title},
+			"description": {After: item.Description},
+			"parent_id":   {After: item.ParentId},
+		},
+	})
+
+	return itemId, err
+}
+
+func (r *TodoItemPostgres) GetAll(ctx context.Context, userId, listId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.getPage(ctx, []string{"li.list_id = $1", "ul.user_id = $2", "ti.parent_id IS NULL", "ti.deleted_at IS NULL"},
+		[]interface{}{listId, userId}, filter, "position")
+}
+
+func (r *TodoItemPostgres) GetAllByUser(ctx context.Context, userId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.getPage(ctx, []string{"ul.user_id = $1", "ti.deleted_at IS NULL"}, []interface{}{userId}, filter, "due_at")
+}
+
+func (r *TodoItemPostgres) getPage(ctx context.Context, conditions []string, args []interface{}, filter todolist_app.ItemFilter,
+	defaultSort string) ([]todolist_app.TodoItem, string, error) {
+	page, err := newKeyset(itemSortColumns, filter.Page, defaultSort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	argId := len(args) + 1
+
+	if filter.Q != "" {
+		conditions = append(conditions, fmt.Sprintf("(ti.title ILIKE $%d OR ti.description ILIKE $%d)", argId, argId))
+		args = append(args, likePattern(filter.Q))
+		argId++
+	}
+
+	if filter.Done != nil {
+		conditions = append(conditions, fmt.Sprintf("ti.done = $%d", argId))
+		args = append(args, *filter.Done)
+		argId++
+	}
+
+	if filter.DueBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("ti.due_at < $%d", argId))
+		args = append(args, *filter.DueBefore)
+		argId++
+	}
+
+	if filter.DueAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("ti.due_at > $%d", argId))
+		args = append(args, *filter.DueAfter)
+		argId++
+	}
+
+	if filter.Overdue {
+		conditions = append(conditions, "ti.due_at < now() AND ti.done = false")
+	}
+
+	if filter.MinPriority != nil {
+		conditions = append(conditions, fmt.Sprintf("ti.priority >= $%d", argId))
+		args = append(args, *filter.MinPriority)
+		argId++
+	}
+
+	if filter.Label != nil {
+		// labels are personal, so only the requesting user's labels match
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM %s il INNER JOIN %s l on l.id = il.label_id
+									WHERE il.item_id = ti.id AND il.label_id = $%d AND l.user_id = ul.user_id)`,
+			itemsLabelsTable, labelsTable, argId))
+		args = append(args, *filter.Label)
+		argId++
+	}
+
+	if cond, condArgs := page.where("ti.id", argId); cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	var items []todolist_app.TodoItem
+	query := fmt.Sprintf(`SELECT %s FROM %s ti
+									INNER JOIN %s li on li.item_id = ti.id INNER JOIN %s ul on ul.list_id = li.list_id WHERE %s %s`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable, strings.Join(conditions, " AND "), page.orderBy("ti.id"))
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, "", err
+	}
+
+	items, next := page.next(items, func(i todolist_app.TodoItem) int { return i.Id })
+
+	return items, next, nil
 }
 
-func (r *TodoItemPostgres) GetAll(userId, listId int) ([]todolist_app.TodoItem, error) {
+func (r *TodoItemPostgres) GetById(ctx context.Context, userId, itemId int) (todolist_app.TodoItem, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var item todolist_app.TodoItem
+	query := fmt.Sprintf(`SELECT %s FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable)
+	if err := r.db.GetContext(ctx, &item, query, itemId, userId); err != nil {
+		return item, translateError(err, "item")
+	}
+
+	return item, nil
+}
+
+func (r *TodoItemPostgres) GetSubtasks(ctx context.Context, userId, parentId int) ([]todolist_app.TodoItem, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	var items []todolist_app.TodoItem
-	query := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done FROM %s ti INNER JOIN %s li on li.item_id = ti.id
-									INNER JOIN %s ul on ul.list_id = li.list_id WHERE li.list_id = $1 AND ul.user_id = $2`,
-		todoItemsTable, listsItemsTable, usersListsTable)
-	if err := r.db.Select(&items, query, listId, userId); err != nil {
+	query := fmt.Sprintf(`SELECT %s FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.parent_id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL
+									ORDER BY li.position, ti.id`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable)
+	if err := r.db.SelectContext(ctx, &items, query, parentId, userId); err != nil {
 		return nil, err
 	}
 
 	return items, nil
 }
 
-func (r *TodoItemPostgres) GetById(userId, itemId int) (todolist_app.TodoItem, error) {
-	var item todolist_app.TodoItem
-	query := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done FROM %s ti INNER JOIN %s li on li.item_id = ti.id
-									INNER JOIN %s ul on ul.list_id = li.list_id WHERE ti.id = $1 AND ul.user_id = $2`,
-		todoItemsTable, listsItemsTable, usersListsTable)
-	if err := r.db.Get(&item, query, itemId, userId); err != nil {
-		return item, err
+func (r *TodoItemPostgres) GetRole(ctx context.Context, userId, itemId int) (todolist_app.Role, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var role todolist_app.Role
+	query := fmt.Sprintf(`SELECT ul.role FROM %s ul INNER JOIN %s li on li.list_id = ul.list_id
+									INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.item_id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL`,
+		usersListsTable, listsItemsTable, todoItemsTable)
+	err := r.db.GetContext(ctx, &role, query, itemId, userId)
+
+	return role, translateError(err, "item")
+}
+
+// Delete moves the item and its subtasks to the trash. They share the deletion time,
+// which is how RestoreItem knows which subtasks to bring back.
+func (r *TodoItemPostgres) Delete(ctx context.Context, userId, itemId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
 	}
 
-	return item, nil
+	if err := r.delete(ctx, tx, userId, itemId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *TodoItemPostgres) Delete(userId, itemId int) error {
-	query := fmt.Sprintf(`DELETE FROM %s ti USING %s li, %s ul 
-									WHERE ti.id = li.item_id AND li.list_id = ul.list_id AND ul.user_id = $1 AND ti.id = $2`,
+func (r *TodoItemPostgres) delete(ctx context.Context, tx *sqlx.Tx, userId, itemId int) error {
+	listId, err := r.lockItem(ctx, tx, userId, itemId)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = now() WHERE (id = $1 OR parent_id = $1) AND deleted_at IS NULL`,
+		todoItemsTable)
+	if _, err := tx.ExecContext(ctx, query, itemId); err != nil {
+		return err
+	}
+
+	if err := touchParents(ctx, tx, []int{itemId}); err != nil {
+		return err
+	}
+
+	return recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionDeleted,
+	})
+}
+
+// lockItem locks an item the user can see and returns its list.
+func (r *TodoItemPostgres) lockItem(ctx context.Context, tx *sqlx.Tx, userId, itemId int) (int, error) {
+	var listId int
+	query := fmt.Sprintf(`SELECT li.list_id FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL FOR UPDATE OF ti`,
 		todoItemsTable, listsItemsTable, usersListsTable)
-	_, err := r.db.Exec(query, userId, itemId)
-	return err
+	err := tx.GetContext(ctx, &listId, query, itemId, userId)
+
+	return listId, translateError(err, "item")
 }
 
-func (r *TodoItemPostgres) Update(userId, itemId int, input todolist_app.UpdateItemInput) error {
-	setValues := make([]string, 0)
+func (r *TodoItemPostgres) Update(ctx context.Context, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := r.update(ctx, tx, userId, itemId, input); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemPostgres) update(ctx context.Context, tx *sqlx.Tx, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	var before todolist_app.TodoItem
+	getItemQuery := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done, ti.due_at, ti.remind_at, ti.priority,
+									ti.recurrence, ti.series_id, ti.version, li.list_id
+									FROM %s ti INNER JOIN %s li on li.item_id = ti.id INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL FOR UPDATE OF ti`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &before, getItemQuery, itemId, userId); err != nil {
+		return translateError(err, "item")
+	}
+
+	if input.Version != nil && *input.Version != before.Version {
+		return errItemModified
+	}
+
+	dueAt, remindAt, err := input.Schedule(before.DueAt, before.RemindAt)
+	if err != nil {
+		return err
+	}
+
+	setValues := []string{"version=version+1", "updated_at=now()"}
 	args := make([]interface{}, 0)
 	argId := 1
+	changes := make(todolist_app.Changes)
 
 	if input.Title != nil {
 		setValues = append(setValues, fmt.Sprintf("title=$%d", argId))
 		args = append(args, *input.Title)
 		argId++
+		addChange(changes, "title", before.Title, input.Title)
 	}
 
 	if input.Description != nil {
 		setValues = append(setValues, fmt.Sprintf("description=$%d", argId))
 		args = append(args, *input.Description)
 		argId++
+		addChange(changes, "description", before.Description, input.Description)
 	}
 
 	if input.Done != nil {
-		setValues = append(setValues, fmt.Sprintf("done=$%d", argId))
+		// completing an already done item keeps its completion time
+		setValues = append(setValues, fmt.Sprintf("done=$%d", argId),
+			fmt.Sprintf("completed_at=CASE WHEN $%d THEN COALESCE(completed_at, now()) END", argId))
 		args = append(args, *input.Done)
 		argId++
+		addChange(changes, "done", before.Done, input.Done)
+	}
+
+	if input.DueAt != nil || input.ClearDueAt {
+		setValues = append(setValues, fmt.Sprintf("due_at=$%d", argId))
+		args = append(args, dueAt)
+		argId++
+		addTimeChange(changes, "due_at", before.DueAt, dueAt)
+	}
+
+	if input.RemindAt != nil || input.ClearRemindAt {
+		setValues = append(setValues, fmt.Sprintf("remind_at=$%d", argId))
+		args = append(args, remindAt)
+		argId++
+		addTimeChange(changes, "remind_at", before.RemindAt, remindAt)
+	}
+
+	if input.Priority != nil {
+		setValues = append(setValues, fmt.Sprintf("priority=$%d", argId))
+		args = append(args, *input.Priority)
+		argId++
+		addChange(changes, "priority", before.Priority, input.Priority)
 	}
 
 	setQuery := strings.Join(setValues, ", ")
 
-	query := fmt.Sprintf(`UPDATE %s ti SET %s FROM %s li, %s ul
-									WHERE ti.id = li.item_id AND li.list_id = ul.list_id AND ul.user_id = $%d AND ti.id = $%d`,
-		todoItemsTable, setQuery, listsItemsTable, usersListsTable, argId, argId+1)
-	args = append(args, userId, itemId)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", todoItemsTable, setQuery, argId)
+	args = append(args, itemId)
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return translateError(err, "item")
+	}
+
+	if err := touchParents(ctx, tx, []int{itemId}); err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		if err := recordActivity(ctx, tx, activityEntry{
+			ListId:  before.ListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionUpdated,
+			Changes: changes,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if input.Done != nil && *input.Done && !before.Done && before.Recurrence != nil {
+		return r.createNextOccurrence(ctx, tx, userId, before, input)
+	}
+
+	return nil
+}
+
+// createNextOccurrence spawns the undone item that follows a recurring item input completes, in the same transaction.
+func (r *TodoItemPostgres) createNextOccurrence(ctx context.Context, tx *sqlx.Tx, userId int, item todolist_app.TodoItem,
+	input todolist_app.UpdateItemInput) error {
+	next, ok, err := todolist_app.NextOccurrence(item, input, time.Now())
+	if err != nil || !ok {
+		return err
+	}
+
+	// an earlier completion may have spawned the occurrence already, and inserting it again would fail the transaction
+	var exists bool
+	existsQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE series_id = $1 AND due_at = $2)", todoItemsTable)
+	if err := tx.GetContext(ctx, &exists, existsQuery, next.SeriesId, next.DueAt); err != nil || exists {
+		return err
+	}
+
+	_, err = r.create(ctx, tx, userId, item.ListId, next)
 
-	_, err := r.db.Exec(query, args...)
 	return err
 }
+
+// seriesItemsQuery selects the series of item $1 when user $2 can see it, and the items the user
+// can write to, which limits series changes to lists the user may edit.
+const seriesItemsQuery = `WITH series AS (SELECT COALESCE(ti.series_id, ti.id) AS id FROM %[1]s ti
+									INNER JOIN %[2]s li on li.item_id = ti.id INNER JOIN %[3]s ul on ul.list_id = li.list_id
+									WHERE ti.id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL),
+								writable AS (SELECT li.item_id FROM %[2]s li INNER JOIN %[3]s ul on ul.list_id = li.list_id
+									WHERE ul.user_id = $2 AND ul.role IN ('owner', 'editor'))`
+
+// UpdateSeries applies the input to the undone items of the item's series.
+func (r *TodoItemPostgres) UpdateSeries(ctx context.Context, userId, itemId int, input todolist_app.UpdateSeriesInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	setValues := []string{"version=version+1", "updated_at=now()"}
+	args := []interface{}{itemId, userId}
+	argId := 3
+	changes := make(todolist_app.Changes)
+
+	if input.Title != nil {
+		setValues = append(setValues, fmt.Sprintf("title=$%d", argId))
+		args = append(args, *input.Title)
+		argId++
+		changes["title"] = todolist_app.Change{After: *input.Title}
+	}
+
+	if input.Description != nil {
+		setValues = append(setValues, fmt.Sprintf("description=$%d", argId))
+		args = append(args, *input.Description)
+		argId++
+		changes["description"] = todolist_app.Change{After: *input.Description}
+	}
+
+	if input.Priority != nil {
+		setValues = append(setValues, fmt.Sprintf("priority=$%d", argId))
+		args = append(args, *input.Priority)
+		argId++
+		changes["priority"] = todolist_app.Change{After: *input.Priority}
+	}
+
+	if input.Recurrence != nil {
+		setValues = append(setValues, fmt.Sprintf("recurrence=$%d", argId), "series_id=series.id")
+		args = append(args, *input.Recurrence)
+		argId++
+		changes["recurrence"] = todolist_app.Change{After: *input.Recurrence}
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+
+	query := fmt.Sprintf(seriesItemsQuery+`
+								UPDATE %[1]s t SET %[4]s FROM series
+									WHERE (t.series_id = series.id OR t.id = series.id) AND t.done = false AND t.deleted_at IS NULL
+									AND t.id IN (SELECT item_id FROM writable) RETURNING t.id`,
+		todoItemsTable, listsItemsTable, usersListsTable, setQuery)
+
+	return r.changeSeries(ctx, userId, query, args, activityEntry{Action: todolist_app.ActionSeriesUpdated, Changes: changes})
+}
+
+// StopSeries ends the item's series. Its items are kept, but completing them no longer spawns new ones.
+func (r *TodoItemPostgres) StopSeries(ctx context.Context, userId, itemId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(seriesItemsQuery+`
+								UPDATE %[1]s t SET recurrence = NULL, version = version + 1, updated_at = now() FROM series
+									WHERE (t.series_id = series.id OR t.id = series.id) AND t.recurrence IS NOT NULL
+									AND t.id IN (SELECT item_id FROM writable) RETURNING t.id`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+
+	return r.changeSeries(ctx, userId, query, []interface{}{itemId, userId}, activityEntry{
+		Action:  todolist_app.ActionSeriesStopped,
+		Changes: todolist_app.Changes{"recurrence": {After: nil}},
+	})
+}
+
+// changeSeries runs a series update returning the changed item ids and records it for each item.
+func (r *TodoItemPostgres) changeSeries(ctx context.Context, userId int, query string, args []interface{}, entry activityEntry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var itemIds []int
+	if err := tx.SelectContext(ctx, &itemIds, query, args...); err != nil {
+		tx.Rollback()
+		return translateError(err, "item")
+	}
+
+	if len(itemIds) == 0 {
+		tx.Rollback()
+		return todolist_app.NewNotFoundError("series not found")
+	}
+
+	entry.ActorId = userId
+	entry.Entity = todolist_app.ActivityEntityItem
+	if err := recordItemsActivity(ctx, tx, itemIds, entry); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemPostgres) Reorder(ctx context.Context, userId, listId int, itemIds []int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	positions, err := r.lockPositions(ctx, tx, listId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	changed, err := orderItems(positions, itemIds)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := r.savePositions(ctx, tx, listId, changed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionReordered,
+		Changes: todolist_app.Changes{"item_ids": {After: itemIds}},
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemPostgres) SetPosition(ctx context.Context, userId, itemId int, input todolist_app.ItemPositionInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var listId int
+	getListQuery := fmt.Sprintf(`SELECT li.list_id FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.item_id = $1 AND ti.deleted_at IS NULL`,
+		listsItemsTable, todoItemsTable)
+	if err := tx.GetContext(ctx, &listId, getListQuery, itemId); err != nil {
+		tx.Rollback()
+		return translateError(err, "item")
+	}
+
+	positions, err := r.lockPositions(ctx, tx, listId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	changed, err := placeItem(positions, itemId, input)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := r.savePositions(ctx, tx, listId, changed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	anchorId, before := input.Anchor()
+	anchor := "after_id"
+	if before {
+		anchor = "before_id"
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionRepositioned,
+		Changes: todolist_app.Changes{anchor: {After: anchorId}},
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Move relinks the item and its subtasks to another list and appends them there. The user needs
+// write access to both lists, which is checked under the same transaction as the move itself.
+func (r *TodoItemPostgres) Move(ctx context.Context, userId, itemId, listId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var source struct {
+		ListId   int               `db:"list_id"`
+		ParentId *int              `db:"parent_id"`
+		Role     todolist_app.Role `db:"role"`
+	}
+	getSourceQuery := fmt.Sprintf(`SELECT li.list_id, ti.parent_id, ul.role FROM %s li
+									INNER JOIN %s ti on ti.id = li.item_id INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE li.item_id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL FOR UPDATE OF li`,
+		listsItemsTable, todoItemsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &source, getSourceQuery, itemId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "item")
+	}
+
+	var targetRole todolist_app.Role
+	getTargetQuery := fmt.Sprintf(`SELECT ul.role FROM %s ul INNER JOIN %s tl on tl.id = ul.list_id
+									WHERE ul.list_id = $1 AND ul.user_id = $2 AND tl.deleted_at IS NULL FOR SHARE OF ul`,
+		usersListsTable, todoListsTable)
+	if err := tx.GetContext(ctx, &targetRole, getTargetQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "list")
+	}
+
+	if !source.Role.CanWrite() || !targetRole.CanWrite() {
+		tx.Rollback()
+		return todolist_app.NewForbiddenError("moving the item requires write access to both lists")
+	}
+
+	if source.ParentId != nil {
+		tx.Rollback()
+		return todolist_app.NewValidationError("subtasks are moved together with their parent item")
+	}
+
+	if source.ListId == listId {
+		return tx.Commit()
+	}
+
+	// trashed subtasks move too, so they are restored next to their parent
+	var moved []itemPosition
+	getMovedQuery := fmt.Sprintf(`SELECT li.item_id, li.position FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE ti.id = $1 OR ti.parent_id = $1 ORDER BY li.position, li.item_id`,
+		listsItemsTable, todoItemsTable)
+	if err := tx.SelectContext(ctx, &moved, getMovedQuery, itemId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var last float64
+	getLastQuery := fmt.Sprintf("SELECT COALESCE(MAX(position), 0) FROM %s WHERE list_id = $1", listsItemsTable)
+	if err := tx.GetContext(ctx, &last, getLastQuery, listId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	moveQuery := fmt.Sprintf("UPDATE %s SET list_id = $1, position = $2 WHERE item_id = $3", listsItemsTable)
+	for i, p := range moved {
+		if _, err := tx.ExecContext(ctx, moveQuery, listId, last+float64(i+1)*positionGap, p.ItemId); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	touchQuery := fmt.Sprintf("UPDATE %s SET version = version + 1, updated_at = now() WHERE id = $1 OR parent_id = $1", todoItemsTable)
+	if _, err := tx.ExecContext(ctx, touchQuery, itemId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// both lists show the move in their activity
+	for _, activityListId := range []int{source.ListId, listId} {
+		if err := recordActivity(ctx, tx, activityEntry{
+			ListId:  activityListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionMoved,
+			Changes: todolist_app.Changes{"list_id": {Before: source.ListId, After: listId}},
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lockPositions locks the positions of the list. Trashed items keep their position
+// and are left out, so they cannot be used as anchors.
+func (r *TodoItemPostgres) lockPositions(ctx context.Context, tx *sqlx.Tx, listId int) ([]itemPosition, error) {
+	var positions []itemPosition
+	query := fmt.Sprintf(`SELECT li.item_id, li.position FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.list_id = $1 AND ti.deleted_at IS NULL ORDER BY li.position, li.item_id FOR UPDATE OF li`,
+		listsItemsTable, todoItemsTable)
+	err := tx.SelectContext(ctx, &positions, query, listId)
+
+	return positions, err
+}
+
+// savePositions stores the positions and bumps the version of the items they move.
+func (r *TodoItemPostgres) savePositions(ctx context.Context, tx *sqlx.Tx, listId int, positions []itemPosition) error {
+	query := fmt.Sprintf("UPDATE %s SET position = $1 WHERE list_id = $2 AND item_id = $3", listsItemsTable)
+	itemIds := make([]int, len(positions))
+	for i, p := range positions {
+		if _, err := tx.ExecContext(ctx, query, p.Position, listId, p.ItemId); err != nil {
+			return err
+		}
+		itemIds[i] = p.ItemId
+	}
+
+	return touchItems(ctx, tx, itemIds)
+}
+
+// touchItems bumps the version of items changed through another table, like their position, so an update
+// made with the version read before is refused. Labels are private to their owner and leave the version alone.
+func touchItems(ctx context.Context, tx *sqlx.Tx, itemIds []int) error {
+	query := fmt.Sprintf("UPDATE %s SET version = version + 1, updated_at = now() WHERE id = ANY($1)", todoItemsTable)
+	_, err := tx.ExecContext(ctx, query, pq.Array(itemIds))
+
+	return err
+}
+
+// touchParents bumps the version of the parents of itemIds, since a parent shows its subtasks.
+func touchParents(ctx context.Context, tx *sqlx.Tx, itemIds []int) error {
+	query := fmt.Sprintf(`UPDATE %s SET version = version + 1, updated_at = now()
+									WHERE id IN (SELECT parent_id FROM %s WHERE id = ANY($1))`, todoItemsTable, todoItemsTable)
+	_, err := tx.ExecContext(ctx, query, pq.Array(itemIds))
+
+	return err
+}
+
+// Batch runs the operations on the items of a list in one transaction. In atomic mode the first failing
+// operation rolls back the batch and its error is returned. Otherwise each operation runs under a savepoint,
+// so a failed one is undone on its own and reported in its result. Unexpected errors always fail the batch.
+func (r *TodoItemPostgres) Batch(ctx context.Context, userId, listId int, operations []todolist_app.BatchOperation,
+	atomic bool) ([]todolist_app.BatchResult, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]todolist_app.BatchResult, 0, len(operations))
+	for n, op := range operations {
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		result := todolist_app.BatchResult{Index: n, Op: op.Op}
+		id, err := r.runOperation(ctx, tx, userId, listId, op)
+
+		var e *todolist_app.Error
+		switch {
+		case err == nil:
+			result.Id = id
+		case !errors.As(err, &e):
+			tx.Rollback()
+			return nil, err
+		case atomic:
+			tx.Rollback()
+			return nil, &todolist_app.Error{Code: e.Code, Message: fmt.Sprintf("operation %d: %s", n, e.Message), Err: err}
+		default:
+			result.Id = id
+			result.Error = &todolist_app.BatchError{Code: e.Code, Message: e.Message}
+		}
+
+		if !atomic {
+			savepointQuery := "RELEASE SAVEPOINT batch_operation"
+			if err != nil {
+				savepointQuery = "ROLLBACK TO SAVEPOINT batch_operation"
+			}
+
+			if _, err := tx.ExecContext(ctx, savepointQuery); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, tx.Commit()
+}
+
+func (r *TodoItemPostgres) runOperation(ctx context.Context, tx *sqlx.Tx, userId, listId int, op todolist_app.BatchOperation) (int, error) {
+	if op.Op == todolist_app.BatchOpCreate {
+		return r.create(ctx, tx, userId, listId, *op.Item)
+	}
+
+	// updates and deletes are limited to the items of the batch's list
+	var inList bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.item_id = $1 AND li.list_id = $2 AND ti.deleted_at IS NULL FOR SHARE OF li)`,
+		listsItemsTable, todoItemsTable)
+	if err := tx.GetContext(ctx, &inList, query, *op.Id, listId); err != nil {
+		return *op.Id, err
+	}
+
+	if !inList {
+		return *op.Id, todolist_app.NewNotFoundError("item not found")
+	}
+
+	if op.Op == todolist_app.BatchOpUpdate {
+		return *op.Id, r.update(ctx, tx, userId, *op.Id, *op.Input)
+	}
+
+	return *op.Id, r.delete(ctx, tx, userId, *op.Id)
+}
+
+// SetDone marks every item of the list, subtasks included, as done or not done. It returns
+// the items that changed as they were before.
+func (r *TodoItemPostgres) SetDone(ctx context.Context, userId, listId int, done bool) ([]todolist_app.TodoItem, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []todolist_app.TodoItem
+	getItemsQuery := fmt.Sprintf(`SELECT %s FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE li.list_id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL AND ti.done <> $3
+									ORDER BY li.position, ti.id FOR UPDATE OF ti`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable)
+	if err := tx.SelectContext(ctx, &items, getItemsQuery, listId, userId, done); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return items, tx.Commit()
+	}
+
+	itemIds := make([]int, len(items))
+	for i, item := range items {
+		itemIds[i] = item.Id
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET done = $2, completed_at = CASE WHEN $2 THEN COALESCE(completed_at, now()) END,
+									version = version + 1, updated_at = now() WHERE id = ANY($1)`, todoItemsTable)
+	if _, err := tx.ExecContext(ctx, query, pq.Array(itemIds), done); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := touchParents(ctx, tx, itemIds); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := recordItemsActivity(ctx, tx, itemIds, activityEntry{
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionUpdated,
+		Changes: todolist_app.Changes{"done": {Before: !done, After: done}},
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, item := range items {
+		if done && item.Recurrence != nil {
+			if err := r.createNextOccurrence(ctx, tx, userId, item, todolist_app.UpdateItemInput{}); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	return items, tx.Commit()
+}
+
+// DeleteDone moves the done items of the list to the trash together with their subtasks
+// and returns how many done items were removed.
+func (r *TodoItemPostgres) DeleteDone(ctx context.Context, userId, listId int) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var itemIds []int
+	getItemsQuery := fmt.Sprintf(`SELECT ti.id FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE li.list_id = $1 AND ul.user_id = $2 AND ti.deleted_at IS NULL AND ti.done
+									FOR UPDATE OF ti`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+	if err := tx.SelectContext(ctx, &itemIds, getItemsQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if len(itemIds) == 0 {
+		return 0, tx.Commit()
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = now() WHERE (id = ANY($1) OR parent_id = ANY($1)) AND deleted_at IS NULL`,
+		todoItemsTable)
+	if _, err := tx.ExecContext(ctx, query, pq.Array(itemIds)); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := touchParents(ctx, tx, itemIds); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := recordItemsActivity(ctx, tx, itemIds, activityEntry{
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionDeleted,
+	}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(itemIds), tx.Commit()
+}
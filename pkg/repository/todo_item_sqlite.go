@@ -0,0 +1,976 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type TodoItemSQLite struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewTodoItemSQLite(db *sqlx.DB, timeout time.Duration) *TodoItemSQLite {
+	return &TodoItemSQLite{db: db, timeout: timeout}
+}
+
+func (r *TodoItemSQLite) Create(ctx context.Context, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	itemId, err := r.create(ctx, tx, sqliteNow(), userId, listId, item)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return itemId, tx.Commit()
+}
+
+func (r *TodoItemSQLite) create(ctx context.Context, tx *sqlx.Tx, now time.Time, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	var itemId int
+	createItemQuery := fmt.Sprintf(`INSERT INTO %s (parent_id, title, description, due_at, remind_at, priority, recurrence, series_id,
+									created_at, updated_at)
+									values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`, todoItemsTable)
+
+	row := tx.QueryRowContext(ctx, createItemQuery, item.ParentId, item.Title, item.Description, sqliteTime(item.DueAt),
+		sqliteTime(item.RemindAt), item.Priority, item.Recurrence, item.SeriesId, now, now)
+	if err := row.Scan(&itemId); err != nil {
+		return 0, translateError(err, "item")
+	}
+
+	// the first item of a series gives the series its id
+	if item.Recurrence != nil && item.SeriesId == nil {
+		startSeriesQuery := fmt.Sprintf("UPDATE %s SET series_id = id WHERE id = ?", todoItemsTable)
+		if _, err := tx.ExecContext(ctx, startSeriesQuery, itemId); err != nil {
+			return 0, translateError(err, "item")
+		}
+	}
+
+	createListItemsQuery := fmt.Sprintf(`INSERT INTO %s (list_id, item_id, position)
+									SELECT ?1, ?2, COALESCE(MAX(position), 0) + %d FROM %s WHERE list_id = ?1`,
+		listsItemsTable, positionGap, listsItemsTable)
+	if _, err := tx.ExecContext(ctx, createListItemsQuery, listId, itemId); err != nil {
+		return 0, translateError(err, "item")
+	}
+
+	if item.ParentId != nil {
+		if err := touchSQLiteItems(ctx, tx, now, []int{*item.ParentId}); err != nil {
+			return 0, err
+		}
+	}
+
+	err := recordSQLiteActivity(ctx, tx, now, activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionCreated,
+		Changes: todolist_app.Changes{
+			"title":       {After: item.Title},
+			"description": {After: item.Description},
+			"parent_id":   {After: item.ParentId},
+		},
+	})
+
+	return itemId, err
+}
+
+func (r *TodoItemSQLite) GetAll(ctx context.Context, userId, listId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.getPage(ctx, []string{"li.list_id = ?", "ul.user_id = ?", "ti.parent_id IS NULL", "ti.deleted_at IS NULL"},
+		[]interface{}{listId, userId}, filter, "position")
+}
+
+func (r *TodoItemSQLite) GetAllByUser(ctx context.Context, userId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.getPage(ctx, []string{"ul.user_id = ?", "ti.deleted_at IS NULL"}, []interface{}{userId}, filter, "due_at")
+}
+
+func (r *TodoItemSQLite) getPage(ctx context.Context, conditions []string, args []interface{}, filter todolist_app.ItemFilter,
+	defaultSort string) ([]todolist_app.TodoItem, string, error) {
+	page, err := newKeyset(itemSortColumns, filter.Page, defaultSort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if filter.Q != "" {
+		conditions = append(conditions, `(ti.title LIKE ? ESCAPE '\' OR ti.description LIKE ? ESCAPE '\')`)
+		args = append(args, likePattern(filter.Q), likePattern(filter.Q))
+	}
+
+	if filter.Done != nil {
+		conditions = append(conditions, "ti.done = ?")
+		args = append(args, *filter.Done)
+	}
+
+	if filter.DueBefore != nil {
+		conditions = append(conditions, "ti.due_at < ?")
+		args = append(args, sqliteTime(filter.DueBefore))
+	}
+
+	if filter.DueAfter != nil {
+		conditions = append(conditions, "ti.due_at > ?")
+		args = append(args, sqliteTime(filter.DueAfter))
+	}
+
+	if filter.Overdue {
+		conditions = append(conditions, "ti.due_at < ? AND ti.done = false")
+		args = append(args, sqliteNow())
+	}
+
+	if filter.MinPriority != nil {
+		conditions = append(conditions, "ti.priority >= ?")
+		args = append(args, *filter.MinPriority)
+	}
+
+	if filter.Label != nil {
+		// labels are personal, so only the requesting user's labels match
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM %s il INNER JOIN %s l on l.id = il.label_id
+									WHERE il.item_id = ti.id AND il.label_id = ? AND l.user_id = ul.user_id)`,
+			itemsLabelsTable, labelsTable))
+		args = append(args, *filter.Label)
+	}
+
+	if cond, condArgs := page.sqliteWhere("ti.id"); cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	var items []todolist_app.TodoItem
+	query := fmt.Sprintf(`SELECT %s FROM %s ti
+									INNER JOIN %s li on li.item_id = ti.id INNER JOIN %s ul on ul.list_id = li.list_id WHERE %s %s`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable, strings.Join(conditions, " AND "), page.orderBy("ti.id"))
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, "", err
+	}
+
+	items, next := page.next(items, func(i todolist_app.TodoItem) int { return i.Id })
+
+	return items, next, nil
+}
+
+func (r *TodoItemSQLite) GetById(ctx context.Context, userId, itemId int) (todolist_app.TodoItem, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var item todolist_app.TodoItem
+	query := fmt.Sprintf(`SELECT %s FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable)
+	if err := r.db.GetContext(ctx, &item, query, itemId, userId); err != nil {
+		return item, translateError(err, "item")
+	}
+
+	return item, nil
+}
+
+func (r *TodoItemSQLite) GetSubtasks(ctx context.Context, userId, parentId int) ([]todolist_app.TodoItem, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var items []todolist_app.TodoItem
+	query := fmt.Sprintf(`SELECT %s FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.parent_id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL
+									ORDER BY li.position, ti.id`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable)
+	if err := r.db.SelectContext(ctx, &items, query, parentId, userId); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *TodoItemSQLite) GetRole(ctx context.Context, userId, itemId int) (todolist_app.Role, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var role todolist_app.Role
+	query := fmt.Sprintf(`SELECT ul.role FROM %s ul INNER JOIN %s li on li.list_id = ul.list_id
+									INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.item_id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL`,
+		usersListsTable, listsItemsTable, todoItemsTable)
+	err := r.db.GetContext(ctx, &role, query, itemId, userId)
+
+	return role, translateError(err, "item")
+}
+
+// Delete moves the item and its subtasks to the trash. They share the deletion time,
+// which is how RestoreItem knows which subtasks to bring back.
+func (r *TodoItemSQLite) Delete(ctx context.Context, userId, itemId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := r.delete(ctx, tx, sqliteNow(), userId, itemId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemSQLite) delete(ctx context.Context, tx *sqlx.Tx, now time.Time, userId, itemId int) error {
+	listId, err := r.getList(ctx, tx, userId, itemId)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = ?1 WHERE (id = ?2 OR parent_id = ?2) AND deleted_at IS NULL`,
+		todoItemsTable)
+	if _, err := tx.ExecContext(ctx, query, now, itemId); err != nil {
+		return err
+	}
+
+	if err := touchSQLiteParents(ctx, tx, now, []int{itemId}); err != nil {
+		return err
+	}
+
+	return recordSQLiteActivity(ctx, tx, now, activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionDeleted,
+	})
+}
+
+// getList returns the list of an item the user can see. There is no row to lock, the transaction
+// already holds the write lock of the database.
+func (r *TodoItemSQLite) getList(ctx context.Context, tx *sqlx.Tx, userId, itemId int) (int, error) {
+	var listId int
+	query := fmt.Sprintf(`SELECT li.list_id FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+	err := tx.GetContext(ctx, &listId, query, itemId, userId)
+
+	return listId, translateError(err, "item")
+}
+
+func (r *TodoItemSQLite) Update(ctx context.Context, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := r.update(ctx, tx, sqliteNow(), userId, itemId, input); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemSQLite) update(ctx context.Context, tx *sqlx.Tx, now time.Time, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	var before todolist_app.TodoItem
+	getItemQuery := fmt.Sprintf(`SELECT ti.id, ti.title, ti.description, ti.done, ti.due_at, ti.remind_at, ti.priority,
+									ti.recurrence, ti.series_id, ti.version, li.list_id
+									FROM %s ti INNER JOIN %s li on li.item_id = ti.id INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE ti.id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &before, getItemQuery, itemId, userId); err != nil {
+		return translateError(err, "item")
+	}
+
+	if input.Version != nil && *input.Version != before.Version {
+		return errItemModified
+	}
+
+	dueAt, remindAt, err := input.Schedule(before.DueAt, before.RemindAt)
+	if err != nil {
+		return err
+	}
+
+	setValues := []string{"version=version+1", "updated_at=?"}
+	args := []interface{}{now}
+	changes := make(todolist_app.Changes)
+
+	if input.Title != nil {
+		setValues = append(setValues, "title=?")
+		args = append(args, *input.Title)
+		addChange(changes, "title", before.Title, input.Title)
+	}
+
+	if input.Description != nil {
+		setValues = append(setValues, "description=?")
+		args = append(args, *input.Description)
+		addChange(changes, "description", before.Description, input.Description)
+	}
+
+	if input.Done != nil {
+		// completing an already done item keeps its completion time
+		setValues = append(setValues, "done=?", "completed_at=CASE WHEN ? THEN COALESCE(completed_at, ?) END")
+		args = append(args, *input.Done, *input.Done, now)
+		addChange(changes, "done", before.Done, input.Done)
+	}
+
+	if input.DueAt != nil || input.ClearDueAt {
+		setValues = append(setValues, "due_at=?")
+		args = append(args, sqliteTime(dueAt))
+		addTimeChange(changes, "due_at", before.DueAt, dueAt)
+	}
+
+	if input.RemindAt != nil || input.ClearRemindAt {
+		setValues = append(setValues, "remind_at=?")
+		args = append(args, sqliteTime(remindAt))
+		addTimeChange(changes, "remind_at", before.RemindAt, remindAt)
+	}
+
+	if input.Priority != nil {
+		setValues = append(setValues, "priority=?")
+		args = append(args, *input.Priority)
+		addChange(changes, "priority", before.Priority, input.Priority)
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", todoItemsTable, setQuery)
+	args = append(args, itemId)
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return translateError(err, "item")
+	}
+
+	if err := touchSQLiteParents(ctx, tx, now, []int{itemId}); err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		if err := recordSQLiteActivity(ctx, tx, now, activityEntry{
+			ListId:  before.ListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionUpdated,
+			Changes: changes,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if input.Done != nil && *input.Done && !before.Done && before.Recurrence != nil {
+		return r.createNextOccurrence(ctx, tx, now, userId, before, input)
+	}
+
+	return nil
+}
+
+// createNextOccurrence spawns the undone item that follows a recurring item input completes, in the same transaction.
+func (r *TodoItemSQLite) createNextOccurrence(ctx context.Context, tx *sqlx.Tx, now time.Time, userId int, item todolist_app.TodoItem,
+	input todolist_app.UpdateItemInput) error {
+	next, ok, err := todolist_app.NextOccurrence(item, input, now)
+	if err != nil || !ok {
+		return err
+	}
+
+	// an earlier completion may have spawned the occurrence already, and inserting it again would fail the transaction
+	var exists bool
+	existsQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE series_id = ? AND due_at = ?)", todoItemsTable)
+	if err := tx.GetContext(ctx, &exists, existsQuery, next.SeriesId, sqliteTime(next.DueAt)); err != nil || exists {
+		return err
+	}
+
+	_, err = r.create(ctx, tx, now, userId, item.ListId, next)
+
+	return err
+}
+
+// sqliteSeriesItemsQuery is seriesItemsQuery with the numbered parameters of SQLite: ?1 is the item
+// and ?2 the user, ?3 the time of the change.
+const sqliteSeriesItemsQuery = `WITH series AS (SELECT COALESCE(ti.series_id, ti.id) AS id FROM %[1]s ti
+									INNER JOIN %[2]s li on li.item_id = ti.id INNER JOIN %[3]s ul on ul.list_id = li.list_id
+									WHERE ti.id = ?1 AND ul.user_id = ?2 AND ti.deleted_at IS NULL),
+								writable AS (SELECT li.item_id FROM %[2]s li INNER JOIN %[3]s ul on ul.list_id = li.list_id
+									WHERE ul.user_id = ?2 AND ul.role IN ('owner', 'editor'))`
+
+// UpdateSeries applies the input to the undone items of the item's series.
+func (r *TodoItemSQLite) UpdateSeries(ctx context.Context, userId, itemId int, input todolist_app.UpdateSeriesInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	now := sqliteNow()
+	setValues := []string{"version=version+1", "updated_at=?3"}
+	args := []interface{}{itemId, userId, now}
+	argId := 4
+	changes := make(todolist_app.Changes)
+
+	if input.Title != nil {
+		setValues = append(setValues, fmt.Sprintf("title=?%d", argId))
+		args = append(args, *input.Title)
+		argId++
+		changes["title"] = todolist_app.Change{After: *input.Title}
+	}
+
+	if input.Description != nil {
+		setValues = append(setValues, fmt.Sprintf("description=?%d", argId))
+		args = append(args, *input.Description)
+		argId++
+		changes["description"] = todolist_app.Change{After: *input.Description}
+	}
+
+	if input.Priority != nil {
+		setValues = append(setValues, fmt.Sprintf("priority=?%d", argId))
+		args = append(args, *input.Priority)
+		argId++
+		changes["priority"] = todolist_app.Change{After: *input.Priority}
+	}
+
+	if input.Recurrence != nil {
+		setValues = append(setValues, fmt.Sprintf("recurrence=?%d", argId), "series_id=(SELECT id FROM series)")
+		args = append(args, *input.Recurrence)
+		argId++
+		changes["recurrence"] = todolist_app.Change{After: *input.Recurrence}
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+
+	query := fmt.Sprintf(sqliteSeriesItemsQuery+`
+								UPDATE %[1]s SET %[4]s
+									WHERE (series_id IN (SELECT id FROM series) OR id IN (SELECT id FROM series))
+									AND done = false AND deleted_at IS NULL
+									AND id IN (SELECT item_id FROM writable) RETURNING id`,
+		todoItemsTable, listsItemsTable, usersListsTable, setQuery)
+
+	return r.changeSeries(ctx, userId, now, query, args, activityEntry{Action: todolist_app.ActionSeriesUpdated, Changes: changes})
+}
+
+// StopSeries ends the item's series. Its items are kept, but completing them no longer spawns new ones.
+func (r *TodoItemSQLite) StopSeries(ctx context.Context, userId, itemId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	now := sqliteNow()
+	query := fmt.Sprintf(sqliteSeriesItemsQuery+`
+								UPDATE %[1]s SET recurrence = NULL, version = version + 1, updated_at = ?3
+									WHERE (series_id IN (SELECT id FROM series) OR id IN (SELECT id FROM series))
+									AND recurrence IS NOT NULL
+									AND id IN (SELECT item_id FROM writable) RETURNING id`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+
+	return r.changeSeries(ctx, userId, now, query, []interface{}{itemId, userId, now}, activityEntry{
+		Action:  todolist_app.ActionSeriesStopped,
+		Changes: todolist_app.Changes{"recurrence": {After: nil}},
+	})
+}
+
+// changeSeries runs a series update returning the changed item ids and records it for each item.
+func (r *TodoItemSQLite) changeSeries(ctx context.Context, userId int, now time.Time, query string, args []interface{}, entry activityEntry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var itemIds []int
+	if err := tx.SelectContext(ctx, &itemIds, query, args...); err != nil {
+		tx.Rollback()
+		return translateError(err, "item")
+	}
+
+	if len(itemIds) == 0 {
+		tx.Rollback()
+		return todolist_app.NewNotFoundError("series not found")
+	}
+
+	entry.ActorId = userId
+	entry.Entity = todolist_app.ActivityEntityItem
+	if err := recordSQLiteItemsActivity(ctx, tx, now, itemIds, entry); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemSQLite) Reorder(ctx context.Context, userId, listId int, itemIds []int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	positions, err := r.getPositions(ctx, tx, listId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	changed, err := orderItems(positions, itemIds)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := r.savePositions(ctx, tx, listId, changed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordSQLiteActivity(ctx, tx, sqliteNow(), activityEntry{
+		ListId:  listId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionReordered,
+		Changes: todolist_app.Changes{"item_ids": {After: itemIds}},
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoItemSQLite) SetPosition(ctx context.Context, userId, itemId int, input todolist_app.ItemPositionInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var listId int
+	getListQuery := fmt.Sprintf(`SELECT li.list_id FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.item_id = ? AND ti.deleted_at IS NULL`,
+		listsItemsTable, todoItemsTable)
+	if err := tx.GetContext(ctx, &listId, getListQuery, itemId); err != nil {
+		tx.Rollback()
+		return translateError(err, "item")
+	}
+
+	positions, err := r.getPositions(ctx, tx, listId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	changed, err := placeItem(positions, itemId, input)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := r.savePositions(ctx, tx, listId, changed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	anchorId, before := input.Anchor()
+	anchor := "after_id"
+	if before {
+		anchor = "before_id"
+	}
+
+	if err := recordSQLiteActivity(ctx, tx, sqliteNow(), activityEntry{
+		ListId:  listId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionRepositioned,
+		Changes: todolist_app.Changes{anchor: {After: anchorId}},
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Move relinks the item and its subtasks to another list and appends them there. The user needs
+// write access to both lists, which is checked under the same transaction as the move itself.
+func (r *TodoItemSQLite) Move(ctx context.Context, userId, itemId, listId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var source struct {
+		ListId   int               `db:"list_id"`
+		ParentId *int              `db:"parent_id"`
+		Role     todolist_app.Role `db:"role"`
+	}
+	getSourceQuery := fmt.Sprintf(`SELECT li.list_id, ti.parent_id, ul.role FROM %s li
+									INNER JOIN %s ti on ti.id = li.item_id INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE li.item_id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL`,
+		listsItemsTable, todoItemsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &source, getSourceQuery, itemId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "item")
+	}
+
+	var targetRole todolist_app.Role
+	getTargetQuery := fmt.Sprintf(`SELECT ul.role FROM %s ul INNER JOIN %s tl on tl.id = ul.list_id
+									WHERE ul.list_id = ? AND ul.user_id = ? AND tl.deleted_at IS NULL`,
+		usersListsTable, todoListsTable)
+	if err := tx.GetContext(ctx, &targetRole, getTargetQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "list")
+	}
+
+	if !source.Role.CanWrite() || !targetRole.CanWrite() {
+		tx.Rollback()
+		return todolist_app.NewForbiddenError("moving the item requires write access to both lists")
+	}
+
+	if source.ParentId != nil {
+		tx.Rollback()
+		return todolist_app.NewValidationError("subtasks are moved together with their parent item")
+	}
+
+	if source.ListId == listId {
+		return tx.Commit()
+	}
+
+	// trashed subtasks move too, so they are restored next to their parent
+	var moved []itemPosition
+	getMovedQuery := fmt.Sprintf(`SELECT li.item_id, li.position FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE ti.id = ?1 OR ti.parent_id = ?1 ORDER BY li.position, li.item_id`,
+		listsItemsTable, todoItemsTable)
+	if err := tx.SelectContext(ctx, &moved, getMovedQuery, itemId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var last float64
+	getLastQuery := fmt.Sprintf("SELECT COALESCE(MAX(position), 0) FROM %s WHERE list_id = ?", listsItemsTable)
+	if err := tx.GetContext(ctx, &last, getLastQuery, listId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	moveQuery := fmt.Sprintf("UPDATE %s SET list_id = ?, position = ? WHERE item_id = ?", listsItemsTable)
+	for i, p := range moved {
+		if _, err := tx.ExecContext(ctx, moveQuery, listId, last+float64(i+1)*positionGap, p.ItemId); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	now := sqliteNow()
+	touchQuery := fmt.Sprintf("UPDATE %s SET version = version + 1, updated_at = ?1 WHERE id = ?2 OR parent_id = ?2", todoItemsTable)
+	if _, err := tx.ExecContext(ctx, touchQuery, now, itemId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// both lists show the move in their activity
+	for _, activityListId := range []int{source.ListId, listId} {
+		if err := recordSQLiteActivity(ctx, tx, now, activityEntry{
+			ListId:  activityListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionMoved,
+			Changes: todolist_app.Changes{"list_id": {Before: source.ListId, After: listId}},
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// getPositions returns the positions of the list. Trashed items keep their position
+// and are left out, so they cannot be used as anchors.
+func (r *TodoItemSQLite) getPositions(ctx context.Context, tx *sqlx.Tx, listId int) ([]itemPosition, error) {
+	var positions []itemPosition
+	query := fmt.Sprintf(`SELECT li.item_id, li.position FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.list_id = ? AND ti.deleted_at IS NULL ORDER BY li.position, li.item_id`,
+		listsItemsTable, todoItemsTable)
+	err := tx.SelectContext(ctx, &positions, query, listId)
+
+	return positions, err
+}
+
+// savePositions stores the positions and bumps the version of the items they move.
+func (r *TodoItemSQLite) savePositions(ctx context.Context, tx *sqlx.Tx, listId int, positions []itemPosition) error {
+	query := fmt.Sprintf("UPDATE %s SET position = ? WHERE list_id = ? AND item_id = ?", listsItemsTable)
+	itemIds := make([]int, len(positions))
+	for i, p := range positions {
+		if _, err := tx.ExecContext(ctx, query, p.Position, listId, p.ItemId); err != nil {
+			return err
+		}
+		itemIds[i] = p.ItemId
+	}
+
+	return touchSQLiteItems(ctx, tx, sqliteNow(), itemIds)
+}
+
+// touchSQLiteItems bumps the version of items changed through another table, like their position, so an update
+// made with the version read before is refused. Labels are private to their owner and leave the version alone.
+func touchSQLiteItems(ctx context.Context, tx *sqlx.Tx, now time.Time, itemIds []int) error {
+	if len(itemIds) == 0 {
+		return nil
+	}
+
+	query, args, err := inQuery(fmt.Sprintf("UPDATE %s SET version = version + 1, updated_at = ? WHERE id IN (?)",
+		todoItemsTable), now, itemIds)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// touchSQLiteParents bumps the version of the parents of itemIds, since a parent shows its subtasks.
+func touchSQLiteParents(ctx context.Context, tx *sqlx.Tx, now time.Time, itemIds []int) error {
+	if len(itemIds) == 0 {
+		return nil
+	}
+
+	query, args, err := inQuery(fmt.Sprintf(`UPDATE %s SET version = version + 1, updated_at = ?
+									WHERE id IN (SELECT parent_id FROM %s WHERE id IN (?))`, todoItemsTable, todoItemsTable),
+		now, itemIds)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// Batch runs the operations on the items of a list in one transaction. In atomic mode the first failing
+// operation rolls back the batch and its error is returned. Otherwise each operation runs under a savepoint,
+// so a failed one is undone on its own and reported in its result. Unexpected errors always fail the batch.
+func (r *TodoItemSQLite) Batch(ctx context.Context, userId, listId int, operations []todolist_app.BatchOperation,
+	atomic bool) ([]todolist_app.BatchResult, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := sqliteNow()
+	results := make([]todolist_app.BatchResult, 0, len(operations))
+	for n, op := range operations {
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		result := todolist_app.BatchResult{Index: n, Op: op.Op}
+		id, err := r.runOperation(ctx, tx, now, userId, listId, op)
+
+		var e *todolist_app.Error
+		switch {
+		case err == nil:
+			result.Id = id
+		case !errors.As(err, &e):
+			tx.Rollback()
+			return nil, err
+		case atomic:
+			tx.Rollback()
+			return nil, &todolist_app.Error{Code: e.Code, Message: fmt.Sprintf("operation %d: %s", n, e.Message), Err: err}
+		default:
+			result.Id = id
+			result.Error = &todolist_app.BatchError{Code: e.Code, Message: e.Message}
+		}
+
+		if !atomic {
+			savepointQuery := "RELEASE SAVEPOINT batch_operation"
+			if err != nil {
+				// the savepoint outlives the rollback, releasing it keeps them from nesting
+				savepointQuery = "ROLLBACK TO SAVEPOINT batch_operation; RELEASE SAVEPOINT batch_operation"
+			}
+
+			if _, err := tx.ExecContext(ctx, savepointQuery); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, tx.Commit()
+}
+
+func (r *TodoItemSQLite) runOperation(ctx context.Context, tx *sqlx.Tx, now time.Time, userId, listId int,
+	op todolist_app.BatchOperation) (int, error) {
+	if op.Op == todolist_app.BatchOpCreate {
+		return r.create(ctx, tx, now, userId, listId, *op.Item)
+	}
+
+	// updates and deletes are limited to the items of the batch's list
+	var inList bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s li INNER JOIN %s ti on ti.id = li.item_id
+									WHERE li.item_id = ? AND li.list_id = ? AND ti.deleted_at IS NULL)`,
+		listsItemsTable, todoItemsTable)
+	if err := tx.GetContext(ctx, &inList, query, *op.Id, listId); err != nil {
+		return *op.Id, err
+	}
+
+	if !inList {
+		return *op.Id, todolist_app.NewNotFoundError("item not found")
+	}
+
+	if op.Op == todolist_app.BatchOpUpdate {
+		return *op.Id, r.update(ctx, tx, now, userId, *op.Id, *op.Input)
+	}
+
+	return *op.Id, r.delete(ctx, tx, now, userId, *op.Id)
+}
+
+// SetDone marks every item of the list, subtasks included, as done or not done. It returns
+// the items that changed as they were before.
+func (r *TodoItemSQLite) SetDone(ctx context.Context, userId, listId int, done bool) ([]todolist_app.TodoItem, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []todolist_app.TodoItem
+	getItemsQuery := fmt.Sprintf(`SELECT %s FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE li.list_id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL AND ti.done <> ?
+									ORDER BY li.position, ti.id`,
+		todoItemColumns, todoItemsTable, listsItemsTable, usersListsTable)
+	if err := tx.SelectContext(ctx, &items, getItemsQuery, listId, userId, done); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return items, tx.Commit()
+	}
+
+	itemIds := make([]int, len(items))
+	for i, item := range items {
+		itemIds[i] = item.Id
+	}
+
+	now := sqliteNow()
+	query, args, err := inQuery(fmt.Sprintf(`UPDATE %s SET done = ?, completed_at = CASE WHEN ? THEN COALESCE(completed_at, ?) END,
+									version = version + 1, updated_at = ? WHERE id IN (?)`, todoItemsTable),
+		done, done, now, now, itemIds)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := touchSQLiteParents(ctx, tx, now, itemIds); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := recordSQLiteItemsActivity(ctx, tx, now, itemIds, activityEntry{
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionUpdated,
+		Changes: todolist_app.Changes{"done": {Before: !done, After: done}},
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, item := range items {
+		if done && item.Recurrence != nil {
+			if err := r.createNextOccurrence(ctx, tx, now, userId, item, todolist_app.UpdateItemInput{}); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	return items, tx.Commit()
+}
+
+// DeleteDone moves the done items of the list to the trash together with their subtasks
+// and returns how many done items were removed.
+func (r *TodoItemSQLite) DeleteDone(ctx context.Context, userId, listId int) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var itemIds []int
+	getItemsQuery := fmt.Sprintf(`SELECT ti.id FROM %s ti INNER JOIN %s li on li.item_id = ti.id
+									INNER JOIN %s ul on ul.list_id = li.list_id
+									WHERE li.list_id = ? AND ul.user_id = ? AND ti.deleted_at IS NULL AND ti.done`,
+		todoItemsTable, listsItemsTable, usersListsTable)
+	if err := tx.SelectContext(ctx, &itemIds, getItemsQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if len(itemIds) == 0 {
+		return 0, tx.Commit()
+	}
+
+	now := sqliteNow()
+	query, args, err := inQuery(fmt.Sprintf(`UPDATE %s SET deleted_at = ?
+									WHERE (id IN (?) OR parent_id IN (?)) AND deleted_at IS NULL`, todoItemsTable),
+		now, itemIds, itemIds)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := touchSQLiteParents(ctx, tx, now, itemIds); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := recordSQLiteItemsActivity(ctx, tx, now, itemIds, activityEntry{
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionDeleted,
+	}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(itemIds), tx.Commit()
+}
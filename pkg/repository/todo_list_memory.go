@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	todolist_app "todolist-app"
+)
+
+type TodoListMemory struct {
+	db *MemoryDB
+}
+
+func NewTodoListMemory(db *MemoryDB) *TodoListMemory {
+	return &TodoListMemory{db: db}
+}
+
+func (r *TodoListMemory) Create(ctx context.Context, userId int, list todolist_app.TodoList) (int, error) {
+	var id int
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		if err := checkLength("list", 255, list.Title, list.Description); err != nil {
+			return err
+		}
+
+		if _, ok := tx.users[userId]; !ok {
+			return notFound("user")
+		}
+
+		id = tx.nextId()
+		tx.lists[id] = memoryList{TodoList: todolist_app.TodoList{
+			Id:          id,
+			Title:       list.Title,
+			Description: list.Description,
+			CreatedAt:   tx.now,
+			UpdatedAt:   tx.now,
+			Version:     1,
+		}}
+		tx.members[memoryMember{ListId: id, UserId: userId}] = todolist_app.RoleOwner
+
+		tx.recordActivity(activityEntry{
+			ListId:  id,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityList,
+			Action:  todolist_app.ActionCreated,
+			Changes: todolist_app.Changes{
+				"title":       {After: list.Title},
+				"description": {After: list.Description},
+			},
+		})
+
+		return nil
+	})
+
+	return id, err
+}
+
+func (r *TodoListMemory) GetAll(ctx context.Context, userId int, filter todolist_app.ListFilter) ([]todolist_app.TodoList, string, error) {
+	page, err := newKeyset(listSortColumns, filter.Page, "id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var lists []todolist_app.TodoList
+	err = r.db.read(ctx, func(s *memoryState) error {
+		q := strings.ToLower(filter.Q)
+		for _, list := range s.lists {
+			role, ok := s.role(userId, list.Id)
+			if !ok {
+				continue
+			}
+
+			if q != "" && !strings.Contains(strings.ToLower(list.Title), q) &&
+				!strings.Contains(strings.ToLower(list.Description), q) {
+				continue
+			}
+
+			view := list.TodoList
+			view.Role = role
+			lists = append(lists, view)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	lists, next := page.page(lists, func(l todolist_app.TodoList) int { return l.Id })
+
+	return lists, next, nil
+}
+
+func (r *TodoListMemory) GetById(ctx context.Context, userId, listId int) (todolist_app.TodoList, error) {
+	var list todolist_app.TodoList
+	err := r.db.read(ctx, func(s *memoryState) error {
+		role, ok := s.role(userId, listId)
+		if !ok {
+			return notFound("list")
+		}
+
+		list = s.lists[listId].TodoList
+		list.Role = role
+
+		return nil
+	})
+
+	return list, err
+}
+
+// Delete moves the list and its items to the trash. The items get the deletion time of the list,
+// so RestoreList brings back exactly them and not the items that were trashed before.
+func (r *TodoListMemory) Delete(ctx context.Context, userId, listId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		if _, ok := tx.role(userId, listId); !ok {
+			return notFound("list")
+		}
+
+		now := tx.now
+		list := tx.lists[listId]
+		list.DeletedAt = &now
+		tx.lists[listId] = list
+
+		for id, item := range tx.items {
+			if item.ListId == listId && item.DeletedAt == nil {
+				item.DeletedAt = &now
+				tx.items[id] = item
+			}
+		}
+
+		tx.recordActivity(activityEntry{
+			ListId:  listId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityList,
+			Action:  todolist_app.ActionDeleted,
+		})
+
+		return nil
+	})
+}
+
+func (r *TodoListMemory) Update(ctx context.Context, userId, listId int, input todolist_app.UpdateListInput) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		if _, ok := tx.role(userId, listId); !ok {
+			return notFound("list")
+		}
+
+		list := tx.lists[listId]
+		if input.Version != nil && *input.Version != list.Version {
+			return errListModified
+		}
+
+		changes := make(todolist_app.Changes)
+		addChange(changes, "title", list.Title, input.Title)
+		addChange(changes, "description", list.Description, input.Description)
+
+		if input.Title != nil {
+			list.Title = *input.Title
+		}
+		if input.Description != nil {
+			list.Description = *input.Description
+		}
+
+		if err := checkLength("list", 255, list.Title, list.Description); err != nil {
+			return err
+		}
+
+		list.Version++
+		list.UpdatedAt = tx.now
+		tx.lists[listId] = list
+
+		if len(changes) > 0 {
+			tx.recordActivity(activityEntry{
+				ListId:  listId,
+				ActorId: userId,
+				Entity:  todolist_app.ActivityEntityList,
+				Action:  todolist_app.ActionUpdated,
+				Changes: changes,
+			})
+		}
+
+		return nil
+	})
+}
+
+func (r *TodoListMemory) GetRole(ctx context.Context, userId, listId int) (todolist_app.Role, error) {
+	var role todolist_app.Role
+	err := r.db.read(ctx, func(s *memoryState) error {
+		var ok bool
+		if role, ok = s.role(userId, listId); !ok {
+			return notFound("list")
+		}
+
+		return nil
+	})
+
+	return role, err
+}
+
+func (r *TodoListMemory) GetMembers(ctx context.Context, listId int) ([]todolist_app.ListMember, error) {
+	var members []todolist_app.ListMember
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for m, role := range s.members {
+			if m.ListId != listId {
+				continue
+			}
+
+			user := s.users[m.UserId]
+			members = append(members, todolist_app.ListMember{
+				UserId:   user.Id,
+				Name:     user.Name,
+				Username: user.Username,
+				Role:     role,
+			})
+		}
+
+		return nil
+	})
+
+	sort.Slice(members, func(i, j int) bool { return members[i].UserId < members[j].UserId })
+
+	return members, err
+}
+
+// SaveMember adds the user to the list or changes their role, on behalf of actorId. The last owner
+// of the list cannot be demoted.
+func (r *TodoListMemory) SaveMember(ctx context.Context, actorId, listId int, username string, role todolist_app.Role) (int, error) {
+	var userId int
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		found := false
+		for _, u := range tx.users {
+			if u.Username == username {
+				userId, found = u.Id, true
+				break
+			}
+		}
+
+		if !found {
+			return notFound("user")
+		}
+
+		if _, ok := tx.lists[listId]; !ok {
+			return todolist_app.NewNotFoundError("referenced record not found")
+		}
+
+		if role != todolist_app.RoleOwner && tx.isLastOwner(listId, userId) {
+			return errLastOwner
+		}
+
+		key := memoryMember{ListId: listId, UserId: userId}
+		var before *todolist_app.Role
+		if current, ok := tx.members[key]; ok {
+			before = &current
+		}
+		tx.members[key] = role
+
+		tx.recordActivity(activityEntry{
+			ListId:  listId,
+			ActorId: actorId,
+			Entity:  todolist_app.ActivityEntityMember,
+			Action:  todolist_app.ActionMemberSaved,
+			Changes: todolist_app.Changes{
+				"user_id": {After: userId},
+				"role":    {Before: before, After: role},
+			},
+		})
+
+		return nil
+	})
+
+	return userId, err
+}
+
+// DeleteMember removes userId from the list on behalf of actorId, unless they are its last owner.
+func (r *TodoListMemory) DeleteMember(ctx context.Context, actorId, listId, userId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		if tx.isLastOwner(listId, userId) {
+			return errLastOwner
+		}
+
+		key := memoryMember{ListId: listId, UserId: userId}
+		role, ok := tx.members[key]
+		if !ok {
+			return notFound("member")
+		}
+		delete(tx.members, key)
+
+		tx.recordActivity(activityEntry{
+			ListId:  listId,
+			ActorId: actorId,
+			Entity:  todolist_app.ActivityEntityMember,
+			Action:  todolist_app.ActionMemberRemoved,
+			Changes: todolist_app.Changes{
+				"user_id": {Before: userId},
+				"role":    {Before: role},
+			},
+		})
+
+		return nil
+	})
+}
+
+// isLastOwner reports whether userId is the only owner of the list.
+func (s *memoryState) isLastOwner(listId, userId int) bool {
+	owners, isOwner := 0, false
+	for member, role := range s.members {
+		if member.ListId == listId && role == todolist_app.RoleOwner {
+			owners++
+			isOwner = isOwner || member.UserId == userId
+		}
+	}
+
+	return isOwner && owners == 1
+}
@@ -1,100 +1,379 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"strings"
+	"time"
 	todolist_app "todolist-app"
 )
 
 type TodoListPostgres struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration
 }
 
-func NewTodoListPostgres(db *sqlx.DB) *TodoListPostgres {
-	return &TodoListPostgres{db: db}
+func NewTodoListPostgres(db *sqlx.DB, timeout time.Duration) *TodoListPostgres {
+	return &TodoListPostgres{db: db, timeout: timeout}
 }
 
-func (r *TodoListPostgres) Create(userId int, list todolist_app.TodoList) (int, error) {
-	tx, err := r.db.Begin()
+func (r *TodoListPostgres) Create(ctx context.Context, userId int, list todolist_app.TodoList) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 
 	var id int
 	createListQuery := fmt.Sprintf("INSERT INTO %s (title, description) VALUES ($1, $2) RETURNING id", todoListsTable)
-	row := tx.QueryRow(createListQuery, list.Title, list.Description)
+	row := tx.QueryRowContext(ctx, createListQuery, list.Title, list.Description)
 	if err := row.Scan(&id); err != nil {
 		tx.Rollback()
-		return 0, err
+		return 0, translateError(err, "list")
 	}
 
-	createUsersListQuery := fmt.Sprintf("INSERT INTO %s (user_id, list_id) VALUES ($1, $2)", usersListsTable)
-	_, err = tx.Exec(createUsersListQuery, userId, id)
+	createUsersListQuery := fmt.Sprintf("INSERT INTO %s (user_id, list_id, role) VALUES ($1, $2, $3)", usersListsTable)
+	_, err = tx.ExecContext(ctx, createUsersListQuery, userId, id, todolist_app.RoleOwner)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  id,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionCreated,
+		Changes: todolist_app.Changes{
+			"title":       {After: list.Title},
+			"description": {After: list.Description},
+		},
+	}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
 	return id, tx.Commit()
 }
 
-func (r *TodoListPostgres) GetAll(userId int) ([]todolist_app.TodoList, error) {
+func (r *TodoListPostgres) GetAll(ctx context.Context, userId int, filter todolist_app.ListFilter) ([]todolist_app.TodoList, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	page, err := newKeyset(listSortColumns, filter.Page, "id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	conditions := []string{"ul.user_id = $1", "tl.deleted_at IS NULL"}
+	args := []interface{}{userId}
+	argId := 2
+
+	if filter.Q != "" {
+		conditions = append(conditions, fmt.Sprintf("(tl.title ILIKE $%d OR tl.description ILIKE $%d)", argId, argId))
+		args = append(args, likePattern(filter.Q))
+		argId++
+	}
+
+	if cond, condArgs := page.where("tl.id", argId); cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
 	var lists []todolist_app.TodoList
+	query := fmt.Sprintf("SELECT tl.id, tl.title, tl.description, tl.created_at, tl.updated_at, ul.role, tl.version FROM %s tl INNER JOIN %s ul on tl.id = ul.list_id WHERE %s %s",
+		todoListsTable, usersListsTable, strings.Join(conditions, " AND "), page.orderBy("tl.id"))
+	if err := r.db.SelectContext(ctx, &lists, query, args...); err != nil {
+		return nil, "", err
+	}
 
-	query := fmt.Sprintf("SELECT tl.id, tl.title, tl.description FROM %s tl INNER JOIN %s ul on tl.id = ul.list_id WHERE ul.user_id = $1",
-		todoListsTable, usersListsTable)
-	err := r.db.Select(&lists, query, userId)
+	lists, next := page.next(lists, func(l todolist_app.TodoList) int { return l.Id })
 
-	return lists, err
+	return lists, next, nil
 }
 
-func (r *TodoListPostgres) GetById(userId, listId int) (todolist_app.TodoList, error) {
+func (r *TodoListPostgres) GetById(ctx context.Context, userId, listId int) (todolist_app.TodoList, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	var list todolist_app.TodoList
 
-	query := fmt.Sprintf(`SELECT tl.id, tl.title, tl.description FROM %s tl
-								INNER JOIN %s ul on tl.id = ul.list_id WHERE ul.user_id = $1 AND ul.list_id = $2`,
+	query := fmt.Sprintf(`SELECT tl.id, tl.title, tl.description, tl.created_at, tl.updated_at, ul.role, tl.version FROM %s tl
+								INNER JOIN %s ul on tl.id = ul.list_id
+								WHERE ul.user_id = $1 AND ul.list_id = $2 AND tl.deleted_at IS NULL`,
 		todoListsTable, usersListsTable)
-	err := r.db.Get(&list, query, userId, listId)
+	err := r.db.GetContext(ctx, &list, query, userId, listId)
 
-	return list, err
+	return list, translateError(err, "list")
 }
 
-func (r *TodoListPostgres) Delete(userId, listId int) error {
-	query := fmt.Sprintf("DELETE FROM %s tl USING %s ul WHERE tl.id = ul.list_id AND ul.user_id=$1 AND ul.list_id=$2",
+// Delete moves the list and its items to the trash. The items get the deletion time of the list,
+// so RestoreList brings back exactly them and not the items that were trashed before.
+func (r *TodoListPostgres) Delete(ctx context.Context, userId, listId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	deleteListQuery := fmt.Sprintf(`UPDATE %s tl SET deleted_at = now() FROM %s ul
+								WHERE tl.id = ul.list_id AND ul.user_id=$1 AND ul.list_id=$2 AND tl.deleted_at IS NULL`,
 		todoListsTable, usersListsTable)
-	_, err := r.db.Exec(query, userId, listId)
+	res, err := tx.ExecContext(ctx, deleteListQuery, userId, listId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	return err
+	if err := checkRowsAffected(res, "list"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	deleteItemsQuery := fmt.Sprintf(`UPDATE %s ti SET deleted_at = now() FROM %s li
+								WHERE ti.id = li.item_id AND li.list_id = $1 AND ti.deleted_at IS NULL`,
+		todoItemsTable, listsItemsTable)
+	if _, err := tx.ExecContext(ctx, deleteItemsQuery, listId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionDeleted,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *TodoListPostgres) Update(userId, listId int, input todolist_app.UpdateListInput) error {
-	setValues := make([]string, 0)
+func (r *TodoListPostgres) Update(ctx context.Context, userId, listId int, input todolist_app.UpdateListInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var before todolist_app.TodoList
+	getListQuery := fmt.Sprintf(`SELECT tl.title, tl.description, tl.version FROM %s tl INNER JOIN %s ul on tl.id = ul.list_id
+								WHERE ul.list_id = $1 AND ul.user_id = $2 AND tl.deleted_at IS NULL FOR UPDATE OF tl`,
+		todoListsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &before, getListQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "list")
+	}
+
+	if input.Version != nil && *input.Version != before.Version {
+		tx.Rollback()
+		return errListModified
+	}
+
+	setValues := []string{"version=version+1", "updated_at=now()"}
 	args := make([]interface{}, 0)
 	argId := 1
+	changes := make(todolist_app.Changes)
 
 	if input.Title != nil {
 		setValues = append(setValues, fmt.Sprintf("title=$%d", argId))
 		args = append(args, *input.Title)
 		argId++
+		addChange(changes, "title", before.Title, input.Title)
 	}
 
 	if input.Description != nil {
 		setValues = append(setValues, fmt.Sprintf("description=$%d", argId))
 		args = append(args, *input.Description)
 		argId++
+		addChange(changes, "description", before.Description, input.Description)
 	}
 
 	setQuery := strings.Join(setValues, ", ")
 
-	query := fmt.Sprintf("UPDATE %s tl SET %s FROM %s ul WHERE tl.id = ul.list_id AND ul.list_id=$%d AND ul.user_id=$%d",
-		todoListsTable, setQuery, usersListsTable, argId, argId+1)
-	args = append(args, listId, userId)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id=$%d", todoListsTable, setQuery, argId)
+	args = append(args, listId)
 
 	logrus.Debugf("updateQuery: %s", query)
 	logrus.Debugf("args: %s", args)
 
-	_, err := r.db.Exec(query, args...)
-	return err
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		tx.Rollback()
+		return translateError(err, "list")
+	}
+
+	if len(changes) > 0 {
+		if err := recordActivity(ctx, tx, activityEntry{
+			ListId:  listId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityList,
+			Action:  todolist_app.ActionUpdated,
+			Changes: changes,
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoListPostgres) GetRole(ctx context.Context, userId, listId int) (todolist_app.Role, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var role todolist_app.Role
+	query := fmt.Sprintf(`SELECT ul.role FROM %s ul INNER JOIN %s tl on tl.id = ul.list_id
+								WHERE ul.user_id = $1 AND ul.list_id = $2 AND tl.deleted_at IS NULL`,
+		usersListsTable, todoListsTable)
+	err := r.db.GetContext(ctx, &role, query, userId, listId)
+
+	return role, translateError(err, "list")
+}
+
+func (r *TodoListPostgres) GetMembers(ctx context.Context, listId int) ([]todolist_app.ListMember, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var members []todolist_app.ListMember
+	query := fmt.Sprintf(`SELECT u.id AS user_id, u.name, u.username, ul.role FROM %s ul
+								INNER JOIN %s u on u.id = ul.user_id WHERE ul.list_id = $1 ORDER BY u.id`,
+		usersListsTable, usersTable)
+	err := r.db.SelectContext(ctx, &members, query, listId)
+
+	return members, err
+}
+
+// SaveMember adds the user to the list or changes their role, on behalf of actorId. The last owner
+// of the list cannot be demoted.
+func (r *TodoListPostgres) SaveMember(ctx context.Context, actorId, listId int, username string, role todolist_app.Role) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var userId int
+	getUserQuery := fmt.Sprintf("SELECT id FROM %s WHERE username = $1", usersTable)
+	if err := tx.QueryRowContext(ctx, getUserQuery, username).Scan(&userId); err != nil {
+		tx.Rollback()
+		return 0, translateError(err, "user")
+	}
+
+	if role != todolist_app.RoleOwner {
+		if err := checkLastOwner(ctx, tx, listId, userId); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	var current todolist_app.Role
+	var before *todolist_app.Role
+	getRoleQuery := fmt.Sprintf("SELECT role FROM %s WHERE user_id = $1 AND list_id = $2 FOR UPDATE", usersListsTable)
+	switch err := tx.GetContext(ctx, &current, getRoleQuery, userId, listId); {
+	case err == nil:
+		before = &current
+	case !errors.Is(err, sql.ErrNoRows):
+		tx.Rollback()
+		return 0, err
+	}
+
+	if before != nil {
+		updateRoleQuery := fmt.Sprintf("UPDATE %s SET role = $1 WHERE user_id = $2 AND list_id = $3", usersListsTable)
+		if _, err := tx.ExecContext(ctx, updateRoleQuery, role, userId, listId); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	} else {
+		createUsersListQuery := fmt.Sprintf("INSERT INTO %s (user_id, list_id, role) VALUES ($1, $2, $3)", usersListsTable)
+		if _, err := tx.ExecContext(ctx, createUsersListQuery, userId, listId, role); err != nil {
+			tx.Rollback()
+			return 0, translateError(err, "member")
+		}
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ActorId: actorId,
+		Entity:  todolist_app.ActivityEntityMember,
+		Action:  todolist_app.ActionMemberSaved,
+		Changes: todolist_app.Changes{
+			"user_id": {After: userId},
+			"role":    {Before: before, After: role},
+		},
+	}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return userId, tx.Commit()
+}
+
+// DeleteMember removes userId from the list on behalf of actorId, unless they are its last owner.
+func (r *TodoListPostgres) DeleteMember(ctx context.Context, actorId, listId, userId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := checkLastOwner(ctx, tx, listId, userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE list_id = $1 AND user_id = $2 RETURNING role", usersListsTable)
+	var role todolist_app.Role
+	if err := tx.GetContext(ctx, &role, query, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "member")
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ActorId: actorId,
+		Entity:  todolist_app.ActivityEntityMember,
+		Action:  todolist_app.ActionMemberRemoved,
+		Changes: todolist_app.Changes{
+			"user_id": {Before: userId},
+			"role":    {Before: role},
+		},
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkLastOwner refuses to demote or remove userId when they are the only owner of the list. The owner
+// rows stay locked until the transaction ends, so concurrent changes cannot remove the other owners meanwhile.
+func checkLastOwner(ctx context.Context, tx *sqlx.Tx, listId, userId int) error {
+	var owners []int
+	query := fmt.Sprintf("SELECT user_id FROM %s WHERE list_id = $1 AND role = $2 FOR UPDATE", usersListsTable)
+	if err := tx.SelectContext(ctx, &owners, query, listId, todolist_app.RoleOwner); err != nil {
+		return err
+	}
+
+	if len(owners) == 1 && owners[0] == userId {
+		return errLastOwner
+	}
+
+	return nil
 }
@@ -0,0 +1,381 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type TodoListSQLite struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewTodoListSQLite(db *sqlx.DB, timeout time.Duration) *TodoListSQLite {
+	return &TodoListSQLite{db: db, timeout: timeout}
+}
+
+func (r *TodoListSQLite) Create(ctx context.Context, userId int, list todolist_app.TodoList) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	now := sqliteNow()
+
+	var id int
+	createListQuery := fmt.Sprintf("INSERT INTO %s (title, description, created_at, updated_at) VALUES (?, ?, ?, ?) RETURNING id",
+		todoListsTable)
+	row := tx.QueryRowContext(ctx, createListQuery, list.Title, list.Description, now, now)
+	if err := row.Scan(&id); err != nil {
+		tx.Rollback()
+		return 0, translateError(err, "list")
+	}
+
+	createUsersListQuery := fmt.Sprintf("INSERT INTO %s (user_id, list_id, role) VALUES (?, ?, ?)", usersListsTable)
+	_, err = tx.ExecContext(ctx, createUsersListQuery, userId, id, todolist_app.RoleOwner)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := recordSQLiteActivity(ctx, tx, now, activityEntry{
+		ListId:  id,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionCreated,
+		Changes: todolist_app.Changes{
+			"title":       {After: list.Title},
+			"description": {After: list.Description},
+		},
+	}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *TodoListSQLite) GetAll(ctx context.Context, userId int, filter todolist_app.ListFilter) ([]todolist_app.TodoList, string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	page, err := newKeyset(listSortColumns, filter.Page, "id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	conditions := []string{"ul.user_id = ?", "tl.deleted_at IS NULL"}
+	args := []interface{}{userId}
+
+	if filter.Q != "" {
+		// LIKE ignores the case of ASCII letters only
+		conditions = append(conditions, `(tl.title LIKE ? ESCAPE '\' OR tl.description LIKE ? ESCAPE '\')`)
+		args = append(args, likePattern(filter.Q), likePattern(filter.Q))
+	}
+
+	if cond, condArgs := page.sqliteWhere("tl.id"); cond != "" {
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	var lists []todolist_app.TodoList
+	query := fmt.Sprintf("SELECT tl.id, tl.title, tl.description, tl.created_at, tl.updated_at, ul.role, tl.version FROM %s tl INNER JOIN %s ul on tl.id = ul.list_id WHERE %s %s",
+		todoListsTable, usersListsTable, strings.Join(conditions, " AND "), page.orderBy("tl.id"))
+	if err := r.db.SelectContext(ctx, &lists, query, args...); err != nil {
+		return nil, "", err
+	}
+
+	lists, next := page.next(lists, func(l todolist_app.TodoList) int { return l.Id })
+
+	return lists, next, nil
+}
+
+func (r *TodoListSQLite) GetById(ctx context.Context, userId, listId int) (todolist_app.TodoList, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var list todolist_app.TodoList
+
+	query := fmt.Sprintf(`SELECT tl.id, tl.title, tl.description, tl.created_at, tl.updated_at, ul.role, tl.version FROM %s tl
+								INNER JOIN %s ul on tl.id = ul.list_id
+								WHERE ul.user_id = ? AND ul.list_id = ? AND tl.deleted_at IS NULL`,
+		todoListsTable, usersListsTable)
+	err := r.db.GetContext(ctx, &list, query, userId, listId)
+
+	return list, translateError(err, "list")
+}
+
+// Delete moves the list and its items to the trash. The items get the deletion time of the list,
+// so RestoreList brings back exactly them and not the items that were trashed before.
+func (r *TodoListSQLite) Delete(ctx context.Context, userId, listId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := sqliteNow()
+
+	deleteListQuery := fmt.Sprintf(`UPDATE %s SET deleted_at = ?
+								WHERE id = ? AND deleted_at IS NULL AND id IN (SELECT list_id FROM %s WHERE user_id = ?)`,
+		todoListsTable, usersListsTable)
+	res, err := tx.ExecContext(ctx, deleteListQuery, now, listId, userId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := checkRowsAffected(res, "list"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	deleteItemsQuery := fmt.Sprintf(`UPDATE %s SET deleted_at = ?
+								WHERE deleted_at IS NULL AND id IN (SELECT item_id FROM %s WHERE list_id = ?)`,
+		todoItemsTable, listsItemsTable)
+	if _, err := tx.ExecContext(ctx, deleteItemsQuery, now, listId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordSQLiteActivity(ctx, tx, now, activityEntry{
+		ListId:  listId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionDeleted,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoListSQLite) Update(ctx context.Context, userId, listId int, input todolist_app.UpdateListInput) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var before todolist_app.TodoList
+	getListQuery := fmt.Sprintf(`SELECT tl.title, tl.description, tl.version FROM %s tl INNER JOIN %s ul on tl.id = ul.list_id
+								WHERE ul.list_id = ? AND ul.user_id = ? AND tl.deleted_at IS NULL`,
+		todoListsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &before, getListQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "list")
+	}
+
+	if input.Version != nil && *input.Version != before.Version {
+		tx.Rollback()
+		return errListModified
+	}
+
+	now := sqliteNow()
+	setValues := []string{"version=version+1", "updated_at=?"}
+	args := []interface{}{now}
+	changes := make(todolist_app.Changes)
+
+	if input.Title != nil {
+		setValues = append(setValues, "title=?")
+		args = append(args, *input.Title)
+		addChange(changes, "title", before.Title, input.Title)
+	}
+
+	if input.Description != nil {
+		setValues = append(setValues, "description=?")
+		args = append(args, *input.Description)
+		addChange(changes, "description", before.Description, input.Description)
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id=?", todoListsTable, setQuery)
+	args = append(args, listId)
+
+	logrus.Debugf("updateQuery: %s", query)
+	logrus.Debugf("args: %s", args)
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		tx.Rollback()
+		return translateError(err, "list")
+	}
+
+	if len(changes) > 0 {
+		if err := recordSQLiteActivity(ctx, tx, now, activityEntry{
+			ListId:  listId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityList,
+			Action:  todolist_app.ActionUpdated,
+			Changes: changes,
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *TodoListSQLite) GetRole(ctx context.Context, userId, listId int) (todolist_app.Role, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var role todolist_app.Role
+	query := fmt.Sprintf(`SELECT ul.role FROM %s ul INNER JOIN %s tl on tl.id = ul.list_id
+								WHERE ul.user_id = ? AND ul.list_id = ? AND tl.deleted_at IS NULL`,
+		usersListsTable, todoListsTable)
+	err := r.db.GetContext(ctx, &role, query, userId, listId)
+
+	return role, translateError(err, "list")
+}
+
+func (r *TodoListSQLite) GetMembers(ctx context.Context, listId int) ([]todolist_app.ListMember, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var members []todolist_app.ListMember
+	query := fmt.Sprintf(`SELECT u.id AS user_id, u.name, u.username, ul.role FROM %s ul
+								INNER JOIN %s u on u.id = ul.user_id WHERE ul.list_id = ? ORDER BY u.id`,
+		usersListsTable, usersTable)
+	err := r.db.SelectContext(ctx, &members, query, listId)
+
+	return members, err
+}
+
+// SaveMember adds the user to the list or changes their role, on behalf of actorId. The last owner
+// of the list cannot be demoted.
+func (r *TodoListSQLite) SaveMember(ctx context.Context, actorId, listId int, username string, role todolist_app.Role) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var userId int
+	getUserQuery := fmt.Sprintf("SELECT id FROM %s WHERE username = ?", usersTable)
+	if err := tx.QueryRowContext(ctx, getUserQuery, username).Scan(&userId); err != nil {
+		tx.Rollback()
+		return 0, translateError(err, "user")
+	}
+
+	if role != todolist_app.RoleOwner {
+		if err := checkSQLiteLastOwner(ctx, tx, listId, userId); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	var current todolist_app.Role
+	var before *todolist_app.Role
+	getRoleQuery := fmt.Sprintf("SELECT role FROM %s WHERE user_id = ? AND list_id = ?", usersListsTable)
+	switch err := tx.GetContext(ctx, &current, getRoleQuery, userId, listId); {
+	case err == nil:
+		before = &current
+	case !errors.Is(err, sql.ErrNoRows):
+		tx.Rollback()
+		return 0, err
+	}
+
+	if before != nil {
+		updateRoleQuery := fmt.Sprintf("UPDATE %s SET role = ? WHERE user_id = ? AND list_id = ?", usersListsTable)
+		if _, err := tx.ExecContext(ctx, updateRoleQuery, role, userId, listId); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	} else {
+		createUsersListQuery := fmt.Sprintf("INSERT INTO %s (user_id, list_id, role) VALUES (?, ?, ?)", usersListsTable)
+		if _, err := tx.ExecContext(ctx, createUsersListQuery, userId, listId, role); err != nil {
+			tx.Rollback()
+			return 0, translateError(err, "member")
+		}
+	}
+
+	if err := recordSQLiteActivity(ctx, tx, sqliteNow(), activityEntry{
+		ListId:  listId,
+		ActorId: actorId,
+		Entity:  todolist_app.ActivityEntityMember,
+		Action:  todolist_app.ActionMemberSaved,
+		Changes: todolist_app.Changes{
+			"user_id": {After: userId},
+			"role":    {Before: before, After: role},
+		},
+	}); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return userId, tx.Commit()
+}
+
+// DeleteMember removes userId from the list on behalf of actorId, unless they are its last owner.
+func (r *TodoListSQLite) DeleteMember(ctx context.Context, actorId, listId, userId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSQLiteLastOwner(ctx, tx, listId, userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE list_id = ? AND user_id = ? RETURNING role", usersListsTable)
+	var role todolist_app.Role
+	if err := tx.GetContext(ctx, &role, query, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "member")
+	}
+
+	if err := recordSQLiteActivity(ctx, tx, sqliteNow(), activityEntry{
+		ListId:  listId,
+		ActorId: actorId,
+		Entity:  todolist_app.ActivityEntityMember,
+		Action:  todolist_app.ActionMemberRemoved,
+		Changes: todolist_app.Changes{
+			"user_id": {Before: userId},
+			"role":    {Before: role},
+		},
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkSQLiteLastOwner refuses to demote or remove userId when they are the only owner of the list.
+// The transaction already holds the write lock of the database, so the owners cannot change meanwhile.
+func checkSQLiteLastOwner(ctx context.Context, tx *sqlx.Tx, listId, userId int) error {
+	var owners []int
+	query := fmt.Sprintf("SELECT user_id FROM %s WHERE list_id = ? AND role = ?", usersListsTable)
+	if err := tx.SelectContext(ctx, &owners, query, listId, todolist_app.RoleOwner); err != nil {
+		return err
+	}
+
+	if len(owners) == 1 && owners[0] == userId {
+		return errLastOwner
+	}
+
+	return nil
+}
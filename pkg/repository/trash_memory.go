@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type TrashMemory struct {
+	db *MemoryDB
+}
+
+func NewTrashMemory(db *MemoryDB) *TrashMemory {
+	return &TrashMemory{db: db}
+}
+
+// GetAll returns what the user can restore: deleted lists they own and deleted items
+// of lists they can write to, newest first.
+func (r *TrashMemory) GetAll(ctx context.Context, userId int) ([]todolist_app.TrashEntry, error) {
+	entries := make([]todolist_app.TrashEntry, 0)
+	err := r.db.read(ctx, func(s *memoryState) error {
+		for _, list := range s.lists {
+			role := s.members[memoryMember{ListId: list.Id, UserId: userId}]
+			if list.DeletedAt != nil && role == todolist_app.RoleOwner {
+				entries = append(entries, todolist_app.TrashEntry{
+					Kind:      todolist_app.TrashKindList,
+					Id:        list.Id,
+					ListId:    list.Id,
+					Title:     list.Title,
+					DeletedAt: *list.DeletedAt,
+				})
+			}
+		}
+
+		for _, item := range s.items {
+			role := s.members[memoryMember{ListId: item.ListId, UserId: userId}]
+			if item.DeletedAt == nil || !role.CanWrite() || s.lists[item.ListId].DeletedAt != nil {
+				continue
+			}
+
+			if item.ParentId != nil {
+				if parent, ok := s.items[*item.ParentId]; ok && parent.DeletedAt != nil {
+					continue
+				}
+			}
+
+			entries = append(entries, todolist_app.TrashEntry{
+				Kind:      todolist_app.TrashKindItem,
+				Id:        item.Id,
+				ListId:    item.ListId,
+				Title:     item.Title,
+				DeletedAt: *item.DeletedAt,
+			})
+		}
+
+		return nil
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case !a.DeletedAt.Equal(b.DeletedAt):
+			return a.DeletedAt.After(b.DeletedAt)
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		}
+		return a.Id < b.Id
+	})
+
+	return entries, err
+}
+
+// RestoreList brings back a list the user owns together with the items deleted with it.
+func (r *TrashMemory) RestoreList(ctx context.Context, userId, listId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		list, ok := tx.lists[listId]
+		role := tx.members[memoryMember{ListId: listId, UserId: userId}]
+		if !ok || list.DeletedAt == nil || role != todolist_app.RoleOwner {
+			return notFound("deleted list")
+		}
+
+		deletedAt := *list.DeletedAt
+		list.DeletedAt = nil
+		tx.lists[listId] = list
+
+		for id, item := range tx.items {
+			if item.ListId == listId && item.DeletedAt != nil && item.DeletedAt.Equal(deletedAt) {
+				item.DeletedAt = nil
+				tx.items[id] = item
+			}
+		}
+
+		tx.recordActivity(activityEntry{
+			ListId:  listId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityList,
+			Action:  todolist_app.ActionRestored,
+		})
+
+		return nil
+	})
+}
+
+// RestoreItem brings back an item together with the subtasks deleted with it.
+// Items of a deleted list and subtasks of a deleted item are restored through their parent.
+func (r *TrashMemory) RestoreItem(ctx context.Context, userId, itemId int) error {
+	return r.db.write(ctx, func(tx *memoryState) error {
+		item, ok := tx.items[itemId]
+		role := tx.members[memoryMember{ListId: item.ListId, UserId: userId}]
+		if !ok || item.DeletedAt == nil || !role.CanWrite() {
+			return notFound("deleted item")
+		}
+
+		if tx.lists[item.ListId].DeletedAt != nil {
+			return todolist_app.NewConflictError("the list of the item is deleted, restore the list instead")
+		}
+
+		if item.ParentId != nil {
+			if parent, ok := tx.items[*item.ParentId]; ok && parent.DeletedAt != nil {
+				return todolist_app.NewConflictError("the parent item is deleted, restore it instead")
+			}
+		}
+
+		deletedAt := *item.DeletedAt
+		for id, other := range tx.items {
+			restored := id == itemId || other.ParentId != nil && *other.ParentId == itemId
+			if restored && other.DeletedAt != nil && other.DeletedAt.Equal(deletedAt) {
+				other.DeletedAt = nil
+				tx.items[id] = other
+			}
+		}
+
+		tx.recordActivity(activityEntry{
+			ListId:  item.ListId,
+			ItemId:  &itemId,
+			ActorId: userId,
+			Entity:  todolist_app.ActivityEntityItem,
+			Action:  todolist_app.ActionRestored,
+		})
+
+		return nil
+	})
+}
+
+// Purge permanently removes lists and items that were deleted before the given time,
+// along with the records that reference them.
+func (r *TrashMemory) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.db.write(ctx, func(tx *memoryState) error {
+		removedItems := make(map[int]bool)
+		for id, item := range tx.items {
+			if item.DeletedAt != nil && item.DeletedAt.Before(before) {
+				removedItems[id] = true
+				purged++
+			}
+		}
+
+		removedLists := make(map[int]bool)
+		for id, list := range tx.lists {
+			if list.DeletedAt != nil && list.DeletedAt.Before(before) {
+				removedLists[id] = true
+				purged++
+			}
+		}
+
+		tx.removeRecords(removedLists, removedItems)
+
+		return nil
+	})
+
+	return purged, err
+}
+
+// removeRecords deletes lists and items and cascades like the foreign keys of the schema do:
+// subtasks go with their parent, items with their list. The activity of a removed item stays
+// in the history of its list without the item.
+func (s *memoryState) removeRecords(lists, items map[int]bool) {
+	for changed := true; changed; {
+		changed = false
+		for id, item := range s.items {
+			if items[id] {
+				continue
+			}
+
+			if lists[item.ListId] || item.ParentId != nil && items[*item.ParentId] {
+				items[id] = true
+				changed = true
+			}
+		}
+	}
+
+	for id := range items {
+		delete(s.items, id)
+	}
+
+	for id := range lists {
+		delete(s.lists, id)
+	}
+
+	for m := range s.members {
+		if lists[m.ListId] {
+			delete(s.members, m)
+		}
+	}
+
+	for il := range s.itemsLabels {
+		if items[il.ItemId] {
+			delete(s.itemsLabels, il)
+		}
+	}
+
+	activity := make([]todolist_app.Activity, 0, len(s.activity))
+	for _, a := range s.activity {
+		if lists[a.ListId] {
+			continue
+		}
+
+		if a.ItemId != nil && items[*a.ItemId] {
+			a.ItemId = nil
+		}
+		activity = append(activity, a)
+	}
+	s.activity = activity
+}
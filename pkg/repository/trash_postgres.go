@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+	todolist_app "todolist-app"
+)
+
+type TrashPostgres struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func NewTrashPostgres(db *sqlx.DB, timeout time.Duration) *TrashPostgres {
+	return &TrashPostgres{db: db, timeout: timeout}
+}
+
+// GetAll returns what the user can restore: deleted lists they own and deleted items
+// of lists they can write to, newest first.
+func (r *TrashPostgres) GetAll(ctx context.Context, userId int) ([]todolist_app.TrashEntry, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	entries := make([]todolist_app.TrashEntry, 0)
+	query := fmt.Sprintf(`SELECT '%[1]s' AS kind, tl.id, tl.id AS list_id, tl.title, tl.deleted_at
+								FROM %[3]s tl INNER JOIN %[5]s ul on ul.list_id = tl.id
+								WHERE ul.user_id = $1 AND ul.role = 'owner' AND tl.deleted_at IS NOT NULL
+								UNION ALL
+								SELECT '%[2]s' AS kind, ti.id, li.list_id, ti.title, ti.deleted_at
+								FROM %[4]s ti INNER JOIN %[6]s li on li.item_id = ti.id
+									INNER JOIN %[5]s ul on ul.list_id = li.list_id INNER JOIN %[3]s tl on tl.id = li.list_id
+									LEFT JOIN %[4]s p on p.id = ti.parent_id
+								WHERE ul.user_id = $1 AND ul.role IN ('owner', 'editor') AND ti.deleted_at IS NOT NULL
+									AND tl.deleted_at IS NULL AND (p.id IS NULL OR p.deleted_at IS NULL)
+								ORDER BY deleted_at DESC, kind, id`,
+		todolist_app.TrashKindList, todolist_app.TrashKindItem, todoListsTable, todoItemsTable, usersListsTable,
+		listsItemsTable)
+	err := r.db.SelectContext(ctx, &entries, query, userId)
+
+	return entries, err
+}
+
+// RestoreList brings back a list the user owns together with the items deleted with it.
+func (r *TrashPostgres) RestoreList(ctx context.Context, userId, listId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var deletedAt time.Time
+	getListQuery := fmt.Sprintf(`SELECT tl.deleted_at FROM %s tl INNER JOIN %s ul on ul.list_id = tl.id
+								WHERE tl.id = $1 AND ul.user_id = $2 AND ul.role = 'owner' AND tl.deleted_at IS NOT NULL
+								FOR UPDATE OF tl`,
+		todoListsTable, usersListsTable)
+	if err := tx.GetContext(ctx, &deletedAt, getListQuery, listId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "deleted list")
+	}
+
+	restoreListQuery := fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE id = $1", todoListsTable)
+	if _, err := tx.ExecContext(ctx, restoreListQuery, listId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	restoreItemsQuery := fmt.Sprintf(`UPDATE %s ti SET deleted_at = NULL FROM %s li
+								WHERE ti.id = li.item_id AND li.list_id = $1 AND ti.deleted_at = $2`,
+		todoItemsTable, listsItemsTable)
+	if _, err := tx.ExecContext(ctx, restoreItemsQuery, listId, deletedAt); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  listId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityList,
+		Action:  todolist_app.ActionRestored,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RestoreItem brings back an item together with the subtasks deleted with it.
+// Items of a deleted list and subtasks of a deleted item are restored through their parent.
+func (r *TrashPostgres) RestoreItem(ctx context.Context, userId, itemId int) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var item struct {
+		ListId        int        `db:"list_id"`
+		DeletedAt     time.Time  `db:"deleted_at"`
+		ListDeletedAt *time.Time `db:"list_deleted_at"`
+		ParentDeleted bool       `db:"parent_deleted"`
+	}
+	getItemQuery := fmt.Sprintf(`SELECT li.list_id, ti.deleted_at, tl.deleted_at AS list_deleted_at,
+									COALESCE(p.deleted_at IS NOT NULL, false) AS parent_deleted
+								FROM %[1]s ti INNER JOIN %[2]s li on li.item_id = ti.id
+									INNER JOIN %[3]s ul on ul.list_id = li.list_id INNER JOIN %[4]s tl on tl.id = li.list_id
+									LEFT JOIN %[1]s p on p.id = ti.parent_id
+								WHERE ti.id = $1 AND ul.user_id = $2 AND ul.role IN ('owner', 'editor')
+									AND ti.deleted_at IS NOT NULL
+								FOR UPDATE OF ti`,
+		todoItemsTable, listsItemsTable, usersListsTable, todoListsTable)
+	if err := tx.GetContext(ctx, &item, getItemQuery, itemId, userId); err != nil {
+		tx.Rollback()
+		return translateError(err, "deleted item")
+	}
+
+	if item.ListDeletedAt != nil {
+		tx.Rollback()
+		return todolist_app.NewConflictError("the list of the item is deleted, restore the list instead")
+	}
+
+	if item.ParentDeleted {
+		tx.Rollback()
+		return todolist_app.NewConflictError("the parent item is deleted, restore it instead")
+	}
+
+	restoreQuery := fmt.Sprintf(`UPDATE %s SET deleted_at = NULL
+								WHERE (id = $1 OR parent_id = $1) AND deleted_at = $2`, todoItemsTable)
+	if _, err := tx.ExecContext(ctx, restoreQuery, itemId, item.DeletedAt); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordActivity(ctx, tx, activityEntry{
+		ListId:  item.ListId,
+		ItemId:  &itemId,
+		ActorId: userId,
+		Entity:  todolist_app.ActivityEntityItem,
+		Action:  todolist_app.ActionRestored,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Purge permanently removes lists and items that were deleted before the given time.
+// Items go first, as deleting a list does not cascade to them.
+func (r *TrashPostgres) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	purgeItemsQuery := fmt.Sprintf("DELETE FROM %s WHERE deleted_at < $1", todoItemsTable)
+	res, err := tx.ExecContext(ctx, purgeItemsQuery, before)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	items, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	purgeListsQuery := fmt.Sprintf("DELETE FROM %s WHERE deleted_at < $1", todoListsTable)
+	res, err = tx.ExecContext(ctx, purgeListsQuery, before)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	lists, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return items + lists, tx.Commit()
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	todolist_app "todolist-app"
+	"todolist-app/pkg/repository"
+)
+
+type ActivityService struct {
+	repo     repository.Activity
+	listRepo repository.TodoList
+	itemRepo repository.TodoItem
+}
+
+func NewActivityService(repo repository.Activity, listRepo repository.TodoList, itemRepo repository.TodoItem) *ActivityService {
+	return &ActivityService{repo: repo, listRepo: listRepo, itemRepo: itemRepo}
+}
+
+func (s *ActivityService) GetByList(ctx context.Context, userId, listId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	if err := page.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	if _, err := s.listRepo.GetRole(ctx, userId, listId); err != nil {
+		return nil, "", err
+	}
+
+	return s.repo.GetByList(ctx, listId, page)
+}
+
+func (s *ActivityService) GetByItem(ctx context.Context, userId, itemId int, page todolist_app.Page) ([]todolist_app.Activity, string, error) {
+	if err := page.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	if _, err := s.itemRepo.GetRole(ctx, userId, itemId); err != nil {
+		return nil, "", err
+	}
+
+	return s.repo.GetByItem(ctx, userId, itemId, page)
+}
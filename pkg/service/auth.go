@@ -1,79 +1,245 @@
 package service
 
 import (
-	"crypto/sha1"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
 	"time"
 	todolist_app "todolist-app"
 	"todolist-app/pkg/repository"
 )
 
 const (
-	salt       = "hjqrhjqw124617ajfhajs"
-	signingKey = "qrkjk#4#%35FSFJlja#4353KSFjH"
-	tokenTTL   = 12 * time.Hour
+	tokenTTL        = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	errInvalidCredentials  = todolist_app.NewUnauthorizedError("invalid username or password")
+	errInvalidRefreshToken = todolist_app.NewUnauthorizedError("invalid refresh token")
+	errSessionRevoked      = todolist_app.NewUnauthorizedError("session has been revoked")
 )
 
 type tokenClaims struct {
 	jwt.StandardClaims
-	UserId int `json:"user_id"`
+	UserId    int    `json:"user_id"`
+	SessionId string `json:"sid"`
 }
 
 type AuthService struct {
 	repo repository.Authorization
+	keys *KeySet
 }
 
-func NewAuthService(repo repository.Authorization) *AuthService {
-	return &AuthService{repo: repo}
+func NewAuthService(repo repository.Authorization, keys *KeySet) *AuthService {
+	return &AuthService{repo: repo, keys: keys}
 }
 
-func (s *AuthService) CreateUser(user todolist_app.User) (int, error) {
-	user.Password = generatePasswordHash(user.Password)
-	return s.repo.CreateUser(user)
+func (s *AuthService) CreateUser(ctx context.Context, user todolist_app.User) (int, error) {
+	hash, err := hashPassword(user.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	user.Password = hash
+	return s.repo.CreateUser(ctx, user)
 }
 
-func (s *AuthService) GenerateToken(username, password string) (string, error) {
-	user, err := s.repo.GetUser(username, generatePasswordHash(password))
+func (s *AuthService) GenerateTokens(ctx context.Context, username, password string) (todolist_app.Tokens, error) {
+	user, err := s.authenticate(ctx, username, password)
 	if err != nil {
-		return "", err
+		return todolist_app.Tokens{}, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
-			IssuedAt:  time.Now().Unix(),
-		},
-		user.Id,
-	})
+	sessionId, err := randomString(16)
+	if err != nil {
+		return todolist_app.Tokens{}, err
+	}
+
+	refreshToken, token, err := newRefreshToken(user.Id, sessionId)
+	if err != nil {
+		return todolist_app.Tokens{}, err
+	}
 
-	return token.SignedString([]byte(signingKey))
+	if err := s.repo.CreateRefreshToken(ctx, token); err != nil {
+		return todolist_app.Tokens{}, err
+	}
+
+	return s.issueTokens(user.Id, sessionId, refreshToken)
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
+// RefreshTokens exchanges a refresh token for a new token pair. Every refresh token can be used
+// only once: presenting it again means it has leaked, so the whole session is revoked.
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (todolist_app.Tokens, error) {
+	token, err := s.getRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return todolist_app.Tokens{}, err
+	}
+
+	if token.UsedAt != nil {
+		if err := s.repo.RevokeSession(ctx, token.SessionId); err != nil {
+			return todolist_app.Tokens{}, err
 		}
+		return todolist_app.Tokens{}, errSessionRevoked
+	}
 
-		return []byte(signingKey), nil
-	})
+	nextRefreshToken, next, err := newRefreshToken(token.UserId, token.SessionId)
 	if err != nil {
-		return 0, err
+		return todolist_app.Tokens{}, err
+	}
+
+	if err := s.repo.RotateRefreshToken(ctx, token.Id, next); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// lost the race against another refresh with the same token
+			if err := s.repo.RevokeSession(ctx, token.SessionId); err != nil {
+				return todolist_app.Tokens{}, err
+			}
+			return todolist_app.Tokens{}, errSessionRevoked
+		}
+		return todolist_app.Tokens{}, err
+	}
+
+	return s.issueTokens(token.UserId, token.SessionId, nextRefreshToken)
+}
+
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	token, err := s.getRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RevokeSession(ctx, token.SessionId)
+}
+
+func (s *AuthService) ParseToken(ctx context.Context, accessToken string) (int, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, s.keys.keyFunc)
+	if err != nil {
+		return 0, &todolist_app.Error{Code: todolist_app.CodeUnauthorized, Message: "invalid access token", Err: err}
 	}
 
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return 0, errors.New("token claims are not of type *tokenClaims")
+		return 0, todolist_app.NewUnauthorizedError("token claims are not of type *tokenClaims")
+	}
+
+	active, err := s.repo.IsSessionActive(ctx, claims.SessionId)
+	if err != nil {
+		return 0, err
+	}
+
+	if !active {
+		return 0, errSessionRevoked
 	}
 
 	return claims.UserId, nil
 }
 
-func generatePasswordHash(password string) string {
-	hash := sha1.New()
-	hash.Write([]byte(password))
+func (s *AuthService) JWKS() JWKSet {
+	return s.keys.JWKS()
+}
+
+func (s *AuthService) authenticate(ctx context.Context, username, password string) (todolist_app.User, error) {
+	user, err := s.repo.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// a username that does not exist must not answer faster than a wrong password
+			verifyPassword(password, dummyPasswordHash())
+			return user, errInvalidCredentials
+		}
+		return user, err
+	}
+
+	ok, needsRehash, err := verifyPassword(password, user.Password)
+	if err != nil {
+		return user, err
+	}
+
+	if !ok {
+		return user, errInvalidCredentials
+	}
+
+	if needsRehash {
+		// the user proved the password, so an outdated hash can be upgraded transparently
+		if hash, err := hashPassword(password); err != nil {
+			logrus.Errorf("failed to rehash password of user %d: %s", user.Id, err.Error())
+		} else if err := s.repo.UpdatePasswordHash(ctx, user.Id, hash); err != nil {
+			logrus.Errorf("failed to store rehashed password of user %d: %s", user.Id, err.Error())
+		}
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) getRefreshToken(ctx context.Context, refreshToken string) (todolist_app.RefreshToken, error) {
+	token, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return token, errInvalidRefreshToken
+		}
+		return token, err
+	}
+
+	if token.RevokedAt != nil {
+		return token, errSessionRevoked
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return token, errInvalidRefreshToken
+	}
+
+	return token, nil
+}
+
+func (s *AuthService) issueTokens(userId int, sessionId, refreshToken string) (todolist_app.Tokens, error) {
+	accessToken, err := s.keys.sign(&tokenClaims{
+		jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		userId,
+		sessionId,
+	})
+	if err != nil {
+		return todolist_app.Tokens{}, err
+	}
+
+	return todolist_app.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(tokenTTL.Seconds()),
+	}, nil
+}
+
+func newRefreshToken(userId int, sessionId string) (string, todolist_app.RefreshToken, error) {
+	refreshToken, err := randomString(32)
+	if err != nil {
+		return "", todolist_app.RefreshToken{}, err
+	}
+
+	return refreshToken, todolist_app.RefreshToken{
+		UserId:    userId,
+		SessionId: sessionId,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}, nil
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	todolist_app "todolist-app"
+	"todolist-app/pkg/repository"
+)
+
+// rehashCounter records the password hashes the service stores.
+type rehashCounter struct {
+	repository.Authorization
+	updates int
+}
+
+func (r *rehashCounter) UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error {
+	r.updates++
+	return r.Authorization.UpdatePasswordHash(ctx, userId, passwordHash)
+}
+
+func newTestAuthService() (*AuthService, *rehashCounter) {
+	repo := &rehashCounter{Authorization: repository.NewAuthMemory(repository.NewMemoryDB())}
+	return NewAuthService(repo, nil), repo
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestAuthService()
+
+	if _, err := s.CreateUser(ctx, todolist_app.User{Name: "Alice", Username: "alice", Password: "secret"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := s.authenticate(ctx, "alice", "secret"); err != nil {
+		t.Fatalf("authenticate with the right password: %v", err)
+	}
+
+	_, err := s.authenticate(ctx, "alice", "wrong")
+	if todolist_app.ErrorCodeOf(err) != todolist_app.CodeUnauthorized {
+		t.Fatalf("authenticate with a wrong password returned %v", err)
+	}
+
+	_, err = s.authenticate(ctx, "bob", "secret")
+	if err != errInvalidCredentials {
+		t.Fatalf("authenticate of an unknown username returned %v, want errInvalidCredentials", err)
+	}
+
+	if repo.updates != 0 {
+		t.Fatalf("a current hash was stored again %d times", repo.updates)
+	}
+}
+
+func TestAuthenticateLegacyHash(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestAuthService()
+
+	legacy := todolist_app.User{Name: "Alice", Username: "alice", Password: legacyPasswordHash("secret")}
+	if _, err := repo.CreateUser(ctx, legacy); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := s.authenticate(ctx, "alice", "wrong"); err != errInvalidCredentials {
+		t.Fatalf("authenticate with a wrong password returned %v", err)
+	}
+
+	if repo.updates != 0 {
+		t.Fatal("a wrong password upgraded the legacy hash")
+	}
+
+	if _, err := s.authenticate(ctx, "alice", "secret"); err != nil {
+		t.Fatalf("authenticate against a legacy hash: %v", err)
+	}
+
+	if repo.updates != 1 {
+		t.Fatalf("the legacy hash was replaced %d times, want once", repo.updates)
+	}
+
+	user, err := repo.GetUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+
+	if !strings.HasPrefix(user.Password, argon2Prefix) {
+		t.Fatalf("the password hash is %q after signing in", user.Password)
+	}
+
+	if _, err := s.authenticate(ctx, "alice", "secret"); err != nil || repo.updates != 1 {
+		t.Fatalf("authenticate against the upgraded hash = %v with %d updates", err, repo.updates)
+	}
+}
+
+func TestAuthenticateUnknownUsername(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAuthService()
+
+	if _, err := s.CreateUser(ctx, todolist_app.User{Name: "Alice", Username: "alice", Password: "secret"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// warm up the dummy hash, so its creation is not measured
+	s.authenticate(ctx, "bob", "secret")
+
+	elapsed := func(username string) time.Duration {
+		start := time.Now()
+		s.authenticate(ctx, username, "wrong")
+		return time.Since(start)
+	}
+
+	// skipping the argon2 comparison makes an unknown username answer orders of magnitude faster
+	wrongPassword, unknownUsername := elapsed("alice"), elapsed("bob")
+	if unknownUsername < wrongPassword/4 {
+		t.Fatalf("an unknown username is rejected in %s, a wrong password in %s", unknownUsername, wrongPassword)
+	}
+}
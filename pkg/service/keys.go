@@ -0,0 +1,247 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"sort"
+)
+
+// KeyConfig describes a signing key in the auth.keys section of the config. HS256 keys read
+// their secret from the environment variable named by SecretEnv, RS256 and EdDSA keys are
+// read from PEM files. A key with only a public key file can verify tokens but not sign them,
+// which is how a rotated-out key stays valid until its tokens expire.
+type KeyConfig struct {
+	Id             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"`
+	SecretEnv      string `mapstructure:"secret_env"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+type KeySet struct {
+	signing *signingKey
+	keys    map[string]*signingKey
+}
+
+func NewKeySet(signingKeyId string, configs []KeyConfig) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*signingKey, len(configs))}
+
+	for _, cfg := range configs {
+		if cfg.Id == "" {
+			return nil, errors.New("signing key without kid")
+		}
+
+		if _, ok := set.keys[cfg.Id]; ok {
+			return nil, fmt.Errorf("duplicate signing key %q", cfg.Id)
+		}
+
+		key, err := loadKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", cfg.Id, err)
+		}
+		set.keys[cfg.Id] = key
+	}
+
+	signing, ok := set.keys[signingKeyId]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingKeyId)
+	}
+
+	if signing.signKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyId)
+	}
+	set.signing = signing
+
+	return set, nil
+}
+
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+
+	return token.SignedString(k.signing.signKey)
+}
+
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public keys other services need to verify access tokens.
+// Symmetric keys are never published.
+func (k *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(k.keys))}
+
+	for _, key := range k.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+
+	return set
+}
+
+func loadKey(cfg KeyConfig) (*signingKey, error) {
+	key := &signingKey{id: cfg.Id}
+
+	switch cfg.Algorithm {
+	case "HS256":
+		secret := os.Getenv(cfg.SecretEnv)
+		if cfg.SecretEnv == "" || secret == "" {
+			return nil, errors.New("secret_env must name a non-empty environment variable")
+		}
+
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(secret)
+		key.verifyKey = key.signKey
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if err := loadKeyPair(key, cfg); err != nil {
+			return nil, err
+		}
+
+		if _, ok := key.verifyKey.(*rsa.PublicKey); !ok {
+			return nil, errors.New("not an RSA key")
+		}
+	case "EdDSA":
+		key.method = SigningMethodEdDSA
+		if err := loadKeyPair(key, cfg); err != nil {
+			return nil, err
+		}
+
+		if _, ok := key.verifyKey.(ed25519.PublicKey); !ok {
+			return nil, errors.New("not an Ed25519 key")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	return key, nil
+}
+
+func loadKeyPair(key *signingKey, cfg KeyConfig) error {
+	if cfg.PrivateKeyFile != "" {
+		block, err := readPEM(cfg.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+
+		private, err := parsePrivateKey(block)
+		if err != nil {
+			return err
+		}
+
+		key.signKey = private
+		key.verifyKey = private.Public()
+		return nil
+	}
+
+	if cfg.PublicKeyFile != "" {
+		block, err := readPEM(cfg.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return err
+		}
+
+		key.verifyKey = public
+		return nil
+	}
+
+	return errors.New("private_key_file or public_key_file is required")
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+
+	return signer, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	return block, nil
+}
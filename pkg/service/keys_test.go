@@ -0,0 +1,294 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "hmac-secret-that-must-stay-private"
+
+type testKeys struct {
+	configs   []KeyConfig
+	rsaKey    *rsa.PrivateKey
+	rsaPublic []byte
+	edKey     ed25519.PrivateKey
+	oldKey    ed25519.PrivateKey
+}
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+
+	return path
+}
+
+// newTestKeys configures an HS256, an RS256 and an EdDSA key and a rotated-out EdDSA key
+// that only has its public key left.
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	t.Setenv("TEST_JWT_SECRET", testSecret)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating an RSA key: %v", err)
+	}
+
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("encoding the RSA public key: %v", err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating an Ed25519 key: %v", err)
+	}
+
+	edPrivate, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("encoding the Ed25519 key: %v", err)
+	}
+
+	oldPublic, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating an Ed25519 key: %v", err)
+	}
+
+	oldDER, err := x509.MarshalPKIXPublicKey(oldPublic)
+	if err != nil {
+		t.Fatalf("encoding the Ed25519 public key: %v", err)
+	}
+
+	return testKeys{
+		configs: []KeyConfig{
+			{Id: "hs", Algorithm: "HS256", SecretEnv: "TEST_JWT_SECRET"},
+			{Id: "rs", Algorithm: "RS256", PrivateKeyFile: writePEM(t, "rs.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))},
+			{Id: "ed", Algorithm: "EdDSA", PrivateKeyFile: writePEM(t, "ed.pem", "PRIVATE KEY", edPrivate)},
+			{Id: "old", Algorithm: "EdDSA", PublicKeyFile: writePEM(t, "old.pem", "PUBLIC KEY", oldDER)},
+		},
+		rsaKey:    rsaKey,
+		rsaPublic: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublic}),
+		edKey:     edKey,
+		oldKey:    oldKey,
+	}
+}
+
+func testClaims() *tokenClaims {
+	return &tokenClaims{
+		jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix(), IssuedAt: time.Now().Unix()},
+		42,
+		"session",
+	}
+}
+
+func parseTestToken(set *KeySet, token string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, set.keyFunc)
+
+	return claims, err
+}
+
+// signTestToken signs claims with any method and key, under the given kid.
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, testClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing a %s token: %v", method.Alg(), err)
+	}
+
+	return signed
+}
+
+func TestKeySetRoundTrip(t *testing.T) {
+	keys := newTestKeys(t)
+
+	for _, kid := range []string{"hs", "rs", "ed"} {
+		set, err := NewKeySet(kid, keys.configs)
+		if err != nil {
+			t.Fatalf("NewKeySet(%q): %v", kid, err)
+		}
+
+		token, err := set.sign(testClaims())
+		if err != nil {
+			t.Fatalf("signing with %q: %v", kid, err)
+		}
+
+		claims, err := parseTestToken(set, token)
+		if err != nil || claims.UserId != 42 || claims.SessionId != "session" {
+			t.Fatalf("parsing a token signed with %q = %+v, %v", kid, claims, err)
+		}
+
+		// changing the signature invalidates the token
+		signature := token[strings.LastIndex(token, ".")+1:]
+		flipped := strings.Map(func(r rune) rune {
+			if r == 'A' {
+				return 'B'
+			}
+			return 'A'
+		}, signature[:1]) + signature[1:]
+		if _, err := parseTestToken(set, strings.TrimSuffix(token, signature)+flipped); err == nil {
+			t.Fatalf("a token signed with %q verifies with a changed signature", kid)
+		}
+	}
+}
+
+func TestKeySetEdDSA(t *testing.T) {
+	keys := newTestKeys(t)
+
+	set, err := NewKeySet("ed", keys.configs)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	token, err := set.sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	header, err := jwt.DecodeSegment(parts[0])
+	if err != nil {
+		t.Fatalf("decoding the header: %v", err)
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(header, &fields); err != nil || fields["alg"] != "EdDSA" || fields["kid"] != "ed" {
+		t.Fatalf("token header is %s", header)
+	}
+
+	// the signature is a plain Ed25519 signature of the signing string
+	signature, err := jwt.DecodeSegment(parts[2])
+	if err != nil || !ed25519.Verify(keys.edKey.Public().(ed25519.PublicKey), []byte(parts[0]+"."+parts[1]), signature) {
+		t.Fatalf("the EdDSA signature does not verify with the public key: %v", err)
+	}
+
+	if _, err := SigningMethodEdDSA.Sign("data", keys.rsaKey); err != jwt.ErrInvalidKeyType {
+		t.Fatalf("signing with an RSA key returned %v", err)
+	}
+
+	if err := SigningMethodEdDSA.Verify(parts[0]+"."+parts[1], parts[2], &keys.rsaKey.PublicKey); err != jwt.ErrInvalidKeyType {
+		t.Fatalf("verifying with an RSA key returned %v", err)
+	}
+
+	// a key with only its public key left verifies the tokens signed before the rotation
+	token = signTestToken(t, SigningMethodEdDSA, "old", keys.oldKey)
+	if _, err := parseTestToken(set, token); err != nil {
+		t.Fatalf("a token of the rotated-out key is rejected: %v", err)
+	}
+
+	token = signTestToken(t, SigningMethodEdDSA, "old", keys.edKey)
+	if _, err := parseTestToken(set, token); err == nil {
+		t.Fatal("a token signed with another key verifies with the rotated-out key")
+	}
+
+	if _, err := NewKeySet("old", keys.configs); err == nil {
+		t.Fatal("a key without its private key can be used for signing")
+	}
+}
+
+func TestKeySetRejectsTokens(t *testing.T) {
+	keys := newTestKeys(t)
+
+	set, err := NewKeySet("rs", keys.configs)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"unknown kid", signTestToken(t, jwt.SigningMethodRS256, "missing", keys.rsaKey)},
+		{"no kid", signTestToken(t, jwt.SigningMethodRS256, "", keys.rsaKey)},
+		// the public key is known to everyone, so it must never be accepted as an HMAC secret
+		{"HS256 against an RS256 key", signTestToken(t, jwt.SigningMethodHS256, "rs", keys.rsaPublic)},
+		{"RS256 against an HS256 key", signTestToken(t, jwt.SigningMethodRS256, "hs", keys.rsaKey)},
+		{"RS256 against an EdDSA key", signTestToken(t, jwt.SigningMethodRS256, "ed", keys.rsaKey)},
+		{"alg none", signTestToken(t, jwt.SigningMethodNone, "rs", jwt.UnsafeAllowNoneSignatureType)},
+		{"alg none with an HS256 kid", signTestToken(t, jwt.SigningMethodNone, "hs", jwt.UnsafeAllowNoneSignatureType)},
+	}
+
+	for _, tc := range tests {
+		if _, err := parseTestToken(set, tc.token); err == nil {
+			t.Fatalf("%s: the token is accepted", tc.name)
+		}
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+
+	set, err := NewKeySet("hs", keys.configs)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	jwks := set.JWKS()
+
+	var kids []string
+	for _, key := range jwks.Keys {
+		kids = append(kids, fmt.Sprintf("%s:%s:%s", key.Kid, key.Kty, key.Alg))
+	}
+
+	if want := []string{"ed:OKP:EdDSA", "old:OKP:EdDSA", "rs:RSA:RS256"}; fmt.Sprint(kids) != fmt.Sprint(want) {
+		t.Fatalf("JWKS holds %v, want %v", kids, want)
+	}
+
+	for _, key := range jwks.Keys {
+		switch key.Kid {
+		case "rs":
+			if key.N != base64.RawURLEncoding.EncodeToString(keys.rsaKey.N.Bytes()) || key.E != "AQAB" {
+				t.Fatalf("JWKS holds the RSA key %+v", key)
+			}
+		case "ed":
+			if key.Crv != "Ed25519" || key.X != base64.RawURLEncoding.EncodeToString(keys.edKey.Public().(ed25519.PublicKey)) {
+				t.Fatalf("JWKS holds the Ed25519 key %+v", key)
+			}
+		}
+	}
+
+	// neither the HMAC secret nor a private key may leak into the published set
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("encoding the JWKS: %v", err)
+	}
+
+	for _, secret := range [][]byte{
+		[]byte(testSecret),
+		[]byte(base64.RawURLEncoding.EncodeToString([]byte(testSecret))),
+		[]byte(base64.RawURLEncoding.EncodeToString(keys.edKey.Seed())),
+		[]byte(base64.RawURLEncoding.EncodeToString(keys.rsaKey.D.Bytes())),
+	} {
+		if strings.Contains(string(data), string(secret)) {
+			t.Fatalf("JWKS %s contains secret material", data)
+		}
+	}
+
+	for _, field := range []string{`"d"`, `"k"`, `"p"`, `"q"`} {
+		if strings.Contains(string(data), field+":") {
+			t.Fatalf("JWKS %s has the private field %s", data, field)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	todolist_app "todolist-app"
+	"todolist-app/pkg/repository"
+)
+
+type LabelService struct {
+	repo     repository.Label
+	itemRepo repository.TodoItem
+}
+
+func NewLabelService(repo repository.Label, itemRepo repository.TodoItem) *LabelService {
+	return &LabelService{repo: repo, itemRepo: itemRepo}
+}
+
+func (s *LabelService) Create(ctx context.Context, userId int, label todolist_app.Label) (int, error) {
+	return s.repo.Create(ctx, userId, label)
+}
+
+func (s *LabelService) GetAll(ctx context.Context, userId int) ([]todolist_app.Label, error) {
+	return s.repo.GetAll(ctx, userId)
+}
+
+func (s *LabelService) GetById(ctx context.Context, userId, labelId int) (todolist_app.Label, error) {
+	return s.repo.GetById(ctx, userId, labelId)
+}
+
+func (s *LabelService) Update(ctx context.Context, userId, labelId int, input todolist_app.UpdateLabelInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	return s.repo.Update(ctx, userId, labelId, input)
+}
+
+func (s *LabelService) Delete(ctx context.Context, userId, labelId int) error {
+	return s.repo.Delete(ctx, userId, labelId)
+}
+
+func (s *LabelService) GetByItem(ctx context.Context, userId, itemId int) ([]todolist_app.Label, error) {
+	if _, err := s.itemRepo.GetRole(ctx, userId, itemId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByItem(ctx, userId, itemId)
+}
+
+// Attach puts one of the user's labels on an item. Labels are private to the user,
+// so any member of the item's list may label it, viewers included.
+func (s *LabelService) Attach(ctx context.Context, userId, itemId int, input todolist_app.AttachLabelInput) error {
+	if _, err := s.itemRepo.GetRole(ctx, userId, itemId); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.GetById(ctx, userId, input.LabelId); err != nil {
+		return err
+	}
+
+	return s.repo.Attach(ctx, itemId, input.LabelId)
+}
+
+func (s *LabelService) Detach(ctx context.Context, userId, itemId, labelId int) error {
+	if _, err := s.itemRepo.GetRole(ctx, userId, itemId); err != nil {
+		return err
+	}
+
+	return s.repo.Detach(ctx, userId, itemId, labelId)
+}
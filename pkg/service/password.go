@@ -0,0 +1,117 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+	"sync"
+)
+
+// Passwords are stored in the PHC string format, e.g. $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>,
+// so the parameters can be raised later without breaking existing hashes. Hashes without the
+// $argon2id$ prefix are legacy salted SHA-1 hex digests.
+const (
+	argon2Prefix  = "$argon2id$"
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+
+	legacySalt = "hjqrhjqw124617ajfhajs"
+)
+
+var errInvalidPasswordHash = errors.New("invalid password hash")
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+var currentArgon2Params = argon2Params{memory: argon2Memory, time: argon2Time, threads: argon2Threads}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := currentArgon2Params
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, argon2KeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassword checks the password against a stored hash and reports whether the hash
+// should be replaced with one produced by hashPassword.
+func verifyPassword(password, encoded string) (ok bool, needsRehash bool, err error) {
+	if !strings.HasPrefix(encoded, argon2Prefix) {
+		legacy := legacyPasswordHash(password)
+		return subtle.ConstantTimeCompare([]byte(legacy), []byte(encoded)) == 1, true, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(encoded, argon2Prefix), "$")
+	if len(parts) != 4 {
+		return false, false, errInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errInvalidPasswordHash
+	}
+
+	// argon2 panics on zero rounds or threads instead of returning an error
+	var p argon2Params
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil ||
+		p.time == 0 || p.threads == 0 {
+		return false, false, errInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(salt) == 0 {
+		return false, false, errInvalidPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return false, false, errInvalidPasswordHash
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, p != currentArgon2Params, nil
+}
+
+// dummyPasswordHash returns a hash with the current parameters to verify passwords against when there
+// is no user, so unknown usernames take as long to reject as wrong passwords.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		hash, err := hashPassword("")
+		if err != nil {
+			// a hash that fails to parse is still rejected, only without the argon2 work
+			hash = argon2Prefix
+		}
+		dummyHash = hash
+	})
+
+	return dummyHash
+}
+
+func legacyPasswordHash(password string) string {
+	hash := sha1.New()
+	hash.Write([]byte(password))
+
+	return fmt.Sprintf("%x", hash.Sum([]byte(legacySalt)))
+}
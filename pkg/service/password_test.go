@@ -0,0 +1,109 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=1,p=4$") {
+		t.Fatalf("hashPassword returned %q", hash)
+	}
+
+	ok, needsRehash, err := verifyPassword("correct horse", hash)
+	if !ok || needsRehash || err != nil {
+		t.Fatalf("verifyPassword of the right password = %v, %v, %v", ok, needsRehash, err)
+	}
+
+	ok, _, err = verifyPassword("wrong horse", hash)
+	if ok || err != nil {
+		t.Fatalf("verifyPassword of a wrong password = %v, %v", ok, err)
+	}
+
+	other, err := hashPassword("correct horse")
+	if err != nil || other == hash {
+		t.Fatalf("hashing the password again returned %q, %v, want another salt", other, err)
+	}
+}
+
+func TestVerifyPasswordOutdatedParams(t *testing.T) {
+	hash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+
+	saved := currentArgon2Params
+	currentArgon2Params = argon2Params{memory: 8 * 1024, time: 2, threads: 1}
+	outdated, err := hashPassword("secret")
+	currentArgon2Params = saved
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+
+	ok, needsRehash, err := verifyPassword("secret", outdated)
+	if !ok || !needsRehash || err != nil {
+		t.Fatalf("verifyPassword of an outdated hash = %v, %v, %v, want it to ask for a rehash", ok, needsRehash, err)
+	}
+
+	if _, needsRehash, _ := verifyPassword("secret", hash); needsRehash {
+		t.Fatal("verifyPassword asks to rehash a current hash")
+	}
+}
+
+func TestVerifyPasswordMalformed(t *testing.T) {
+	hash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+
+	malformed := []string{
+		"$argon2id$",
+		"$argon2id$v=19",
+		"$argon2id$v=19$m=65536,t=1,p=4",
+		"$argon2id$v=19$m=65536,t=1,p=4$c2FsdHNhbHQ",
+		hash[:strings.LastIndex(hash, "$")+1],
+		hash + "$extra",
+		"$argon2id$v=18$m=65536,t=1,p=4$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=0,p=4$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=0$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=256$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=x,t=1,p=4$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=4$$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=4$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=65536,t=1,p=4$!!!$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=4$c2FsdHNhbHQ$!!!",
+	}
+
+	for _, encoded := range malformed {
+		ok, _, err := verifyPassword("secret", encoded)
+		if ok || err != errInvalidPasswordHash {
+			t.Fatalf("verifyPassword(%q) = %v, %v, want errInvalidPasswordHash", encoded, ok, err)
+		}
+	}
+}
+
+func TestVerifyLegacyPassword(t *testing.T) {
+	hash := legacyPasswordHash("secret")
+
+	ok, needsRehash, err := verifyPassword("secret", hash)
+	if !ok || !needsRehash || err != nil {
+		t.Fatalf("verifyPassword of a legacy hash = %v, %v, %v, want it to ask for a rehash", ok, needsRehash, err)
+	}
+
+	if ok, _, err := verifyPassword("wrong", hash); ok || err != nil {
+		t.Fatalf("verifyPassword of a wrong password against a legacy hash = %v, %v", ok, err)
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	// the dummy hash has to be a complete hash, or rejecting unknown usernames skips the argon2 work
+	ok, needsRehash, err := verifyPassword("", dummyPasswordHash())
+	if !ok || needsRehash || err != nil {
+		t.Fatalf("verifyPassword of the dummy hash = %v, %v, %v", ok, needsRehash, err)
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	todolist_app "todolist-app"
+	"todolist-app/pkg/repository"
+)
+
+type SearchService struct {
+	repo repository.Search
+}
+
+func NewSearchService(repo repository.Search) *SearchService {
+	return &SearchService{repo: repo}
+}
+
+func (s *SearchService) Search(ctx context.Context, userId int, input todolist_app.SearchInput) ([]todolist_app.SearchHit, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Search(ctx, userId, input)
+}
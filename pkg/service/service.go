@@ -1,42 +1,98 @@
 package service
 
 import (
+	"context"
+	"time"
 	todolist_app "todolist-app"
 	"todolist-app/pkg/repository"
 )
 
 type Authorization interface {
-	CreateUser(user todolist_app.User) (int, error)
-	GenerateToken(username, password string) (string, error)
-	ParseToken(token string) (int, error)
+	CreateUser(ctx context.Context, user todolist_app.User) (int, error)
+	GenerateTokens(ctx context.Context, username, password string) (todolist_app.Tokens, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (todolist_app.Tokens, error)
+	Logout(ctx context.Context, refreshToken string) error
+	ParseToken(ctx context.Context, token string) (int, error)
+	JWKS() JWKSet
 }
 
 type TodoList interface {
-	Create(userId int, list todolist_app.TodoList) (int, error)
-	GetAll(userId int) ([]todolist_app.TodoList, error)
-	GetById(userId, listId int) (todolist_app.TodoList, error)
-	Delete(userId, listId int) error
-	Update(userId, listId int, input todolist_app.UpdateListInput) error
+	Create(ctx context.Context, userId int, list todolist_app.TodoList) (int, error)
+	GetAll(ctx context.Context, userId int, filter todolist_app.ListFilter) ([]todolist_app.TodoList, string, error)
+	GetById(ctx context.Context, userId, listId int) (todolist_app.TodoList, error)
+	Delete(ctx context.Context, userId, listId int) error
+	Update(ctx context.Context, userId, listId int, input todolist_app.UpdateListInput) error
+	GetMembers(ctx context.Context, userId, listId int) ([]todolist_app.ListMember, error)
+	SaveMember(ctx context.Context, userId, listId int, input todolist_app.AddMemberInput) (int, error)
+	DeleteMember(ctx context.Context, userId, listId, memberId int) error
 }
 
 type TodoItem interface {
-	Create(userId, listId int, item todolist_app.TodoItem) (int, error)
-	GetAll(userId, listId int) ([]todolist_app.TodoItem, error)
-	GetById(userId, itemId int) (todolist_app.TodoItem, error)
-	Delete(userId, itemId int) error
-	Update(userId, itemId int, input todolist_app.UpdateItemInput) error
+	Create(ctx context.Context, userId, listId int, item todolist_app.TodoItem) (int, error)
+	GetAll(ctx context.Context, userId, listId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error)
+	GetAllByUser(ctx context.Context, userId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error)
+	GetView(ctx context.Context, userId int, view todolist_app.View, filter todolist_app.ViewFilter) ([]todolist_app.TodoItem, string, error)
+	GetById(ctx context.Context, userId, itemId int) (todolist_app.TodoItem, error)
+	CreateSubtask(ctx context.Context, userId, parentId int, item todolist_app.TodoItem) (int, error)
+	GetSubtasks(ctx context.Context, userId, parentId int) ([]todolist_app.TodoItem, error)
+	Delete(ctx context.Context, userId, itemId int) error
+	Update(ctx context.Context, userId, itemId int, input todolist_app.UpdateItemInput) error
+	UpdateSeries(ctx context.Context, userId, itemId int, input todolist_app.UpdateSeriesInput) error
+	StopSeries(ctx context.Context, userId, itemId int) error
+	Reorder(ctx context.Context, userId, listId int, input todolist_app.ReorderItemsInput) error
+	SetPosition(ctx context.Context, userId, itemId int, input todolist_app.ItemPositionInput) error
+	Move(ctx context.Context, userId, itemId int, input todolist_app.MoveItemInput) error
+	Batch(ctx context.Context, userId, listId int, input todolist_app.BatchInput) ([]todolist_app.BatchResult, error)
+	CompleteAll(ctx context.Context, userId, listId int) (int, error)
+	UncheckAll(ctx context.Context, userId, listId int) (int, error)
+	ClearCompleted(ctx context.Context, userId, listId int) (int, error)
+}
+
+type Label interface {
+	Create(ctx context.Context, userId int, label todolist_app.Label) (int, error)
+	GetAll(ctx context.Context, userId int) ([]todolist_app.Label, error)
+	GetById(ctx context.Context, userId, labelId int) (todolist_app.Label, error)
+	Update(ctx context.Context, userId, labelId int, input todolist_app.UpdateLabelInput) error
+	Delete(ctx context.Context, userId, labelId int) error
+	GetByItem(ctx context.Context, userId, itemId int) ([]todolist_app.Label, error)
+	Attach(ctx context.Context, userId, itemId int, input todolist_app.AttachLabelInput) error
+	Detach(ctx context.Context, userId, itemId, labelId int) error
+}
+
+type Search interface {
+	Search(ctx context.Context, userId int, input todolist_app.SearchInput) ([]todolist_app.SearchHit, error)
+}
+
+type Trash interface {
+	GetAll(ctx context.Context, userId int) ([]todolist_app.TrashEntry, error)
+	RestoreList(ctx context.Context, userId, listId int) error
+	RestoreItem(ctx context.Context, userId, itemId int) error
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+type Activity interface {
+	GetByList(ctx context.Context, userId, listId int, page todolist_app.Page) ([]todolist_app.Activity, string, error)
+	GetByItem(ctx context.Context, userId, itemId int, page todolist_app.Page) ([]todolist_app.Activity, string, error)
 }
 
 type Service struct {
 	Authorization
 	TodoItem
 	TodoList
+	Label
+	Search
+	Trash
+	Activity
 }
 
-func NewService(repos *repository.Repository) *Service {
+func NewService(repos *repository.Repository, keys *KeySet) *Service {
 	return &Service{
-		Authorization: NewAuthService(repos.Authorization),
+		Authorization: NewAuthService(repos.Authorization, keys),
 		TodoList:      NewTodoListService(repos.TodoList),
 		TodoItem:      NewTodoItemService(repos.TodoItem, repos.TodoList),
+		Label:         NewLabelService(repos.Label, repos.TodoItem),
+		Search:        NewSearchService(repos.Search),
+		Trash:         NewTrashService(repos.Trash),
+		Activity:      NewActivityService(repos.Activity, repos.TodoList, repos.TodoItem),
 	}
 }
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"time"
 	todolist_app "todolist-app"
 	"todolist-app/pkg/repository"
 )
@@ -14,27 +17,273 @@ func NewTodoItemService(repo repository.TodoItem, listRepo repository.TodoList)
 	return &TodoItemService{repo: repo, listRepo: listRepo}
 }
 
-func (s *TodoItemService) Create(userId, listId int, item todolist_app.TodoItem) (int, error) {
-	_, err := s.listRepo.GetById(userId, listId)
+func (s *TodoItemService) Create(ctx context.Context, userId, listId int, item todolist_app.TodoItem) (int, error) {
+	if err := checkWriteAccess(s.listRepo.GetRole(ctx, userId, listId)); err != nil {
+		return 0, err
+	}
+
+	// subtasks are only created through CreateSubtask
+	item.ParentId = nil
+	item.SeriesId = nil
+
+	recurrence, err := normalizeRecurrence(item.Recurrence)
 	if err != nil {
+		return 0, err
+	}
+	item.Recurrence = recurrence
+
+	return s.repo.Create(ctx, userId, listId, item)
+}
+
+func (s *TodoItemService) GetAll(ctx context.Context, userId, listId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	return s.repo.GetAll(ctx, userId, listId, filter)
+}
+
+func (s *TodoItemService) GetAllByUser(ctx context.Context, userId int, filter todolist_app.ItemFilter) ([]todolist_app.TodoItem, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	return s.repo.GetAllByUser(ctx, userId, filter)
+}
+
+// GetView runs one of the smart views over every list of the user. Day boundaries
+// are taken in the time zone of the filter.
+func (s *TodoItemService) GetView(ctx context.Context, userId int, view todolist_app.View,
+	filter todolist_app.ViewFilter) ([]todolist_app.TodoItem, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	loc, err := filter.Location()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().In(loc)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	notDone, done := false, true
+
+	itemFilter := todolist_app.ItemFilter{Page: filter.Page}
+	defaultSort := "due_at"
+
+	switch view {
+	case todolist_app.ViewToday:
+		itemFilter.Done = &notDone
+		itemFilter.DueBefore = &tomorrow
+	case todolist_app.ViewUpcoming:
+		after := tomorrow.Add(-time.Nanosecond)
+		before := tomorrow.AddDate(0, 0, todolist_app.UpcomingDays)
+		itemFilter.Done = &notDone
+		itemFilter.DueAfter = &after
+		itemFilter.DueBefore = &before
+	case todolist_app.ViewOverdue:
+		itemFilter.Overdue = true
+	case todolist_app.ViewHighPriority:
+		high := todolist_app.PriorityHigh
+		itemFilter.Done = &notDone
+		itemFilter.MinPriority = &high
+		defaultSort = "-priority"
+	case todolist_app.ViewCompleted:
+		itemFilter.Done = &done
+		defaultSort = "-completed_at"
+	default:
+		return nil, "", todolist_app.NewNotFoundError(fmt.Sprintf("unknown view %q", view))
+	}
+
+	if itemFilter.Sort == "" {
+		itemFilter.Sort = defaultSort
+	}
 
+	return s.repo.GetAllByUser(ctx, userId, itemFilter)
+}
+
+func (s *TodoItemService) GetById(ctx context.Context, userId, itemId int) (todolist_app.TodoItem, error) {
+	return s.repo.GetById(ctx, userId, itemId)
+}
+
+// CreateSubtask adds a checklist item under parentId in the parent's list. Subtasks are one level
+// deep, so a subtask cannot have subtasks of its own.
+func (s *TodoItemService) CreateSubtask(ctx context.Context, userId, parentId int, item todolist_app.TodoItem) (int, error) {
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, parentId)); err != nil {
 		return 0, err
 	}
 
-	return s.repo.Create(listId, item)
+	parent, err := s.repo.GetById(ctx, userId, parentId)
+	if err != nil {
+		return 0, err
+	}
+
+	if parent.ParentId != nil {
+		return 0, todolist_app.NewValidationError("subtasks cannot have subtasks")
+	}
+
+	if item.Recurrence != nil {
+		return 0, todolist_app.NewValidationError("subtasks cannot repeat")
+	}
+
+	item.ParentId = &parent.Id
+	item.SeriesId = nil
+
+	return s.repo.Create(ctx, userId, parent.ListId, item)
+}
+
+func (s *TodoItemService) GetSubtasks(ctx context.Context, userId, parentId int) ([]todolist_app.TodoItem, error) {
+	if _, err := s.repo.GetById(ctx, userId, parentId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSubtasks(ctx, userId, parentId)
+}
+
+func (s *TodoItemService) Delete(ctx context.Context, userId, itemId int) error {
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, itemId)); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, userId, itemId)
+}
+
+func (s *TodoItemService) Update(ctx context.Context, userId, itemId int, input todolist_app.UpdateItemInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, itemId)); err != nil {
+		return err
+	}
+
+	// completing a recurring item spawns its next occurrence in the same transaction
+	return s.repo.Update(ctx, userId, itemId, input)
 }
 
-func (s *TodoItemService) GetAll(userId, listId int) ([]todolist_app.TodoItem, error) {
-	return s.repo.GetAll(userId, listId)
+func (s *TodoItemService) UpdateSeries(ctx context.Context, userId, itemId int, input todolist_app.UpdateSeriesInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	recurrence, err := normalizeRecurrence(input.Recurrence)
+	if err != nil {
+		return err
+	}
+	input.Recurrence = recurrence
+
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, itemId)); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateSeries(ctx, userId, itemId, input)
 }
-func (s *TodoItemService) GetById(userId, itemId int) (todolist_app.TodoItem, error) {
-	return s.repo.GetById(userId, itemId)
+
+func (s *TodoItemService) StopSeries(ctx context.Context, userId, itemId int) error {
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, itemId)); err != nil {
+		return err
+	}
+
+	return s.repo.StopSeries(ctx, userId, itemId)
 }
 
-func (s *TodoItemService) Delete(userId, itemId int) error {
-	return s.repo.Delete(userId, itemId)
+// normalizeRecurrence validates a rule and returns it in the canonical form it is stored in.
+func normalizeRecurrence(rule *string) (*string, error) {
+	if rule == nil {
+		return nil, nil
+	}
+
+	r, err := todolist_app.ParseRecurrence(*rule)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := r.String()
+
+	return &normalized, nil
+}
+
+func (s *TodoItemService) Reorder(ctx context.Context, userId, listId int, input todolist_app.ReorderItemsInput) error {
+	if err := checkWriteAccess(s.listRepo.GetRole(ctx, userId, listId)); err != nil {
+		return err
+	}
+
+	return s.repo.Reorder(ctx, userId, listId, input.ItemIds)
+}
+
+func (s *TodoItemService) SetPosition(ctx context.Context, userId, itemId int, input todolist_app.ItemPositionInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, itemId)); err != nil {
+		return err
+	}
+
+	return s.repo.SetPosition(ctx, userId, itemId, input)
 }
 
-func (s *TodoItemService) Update(userId, itemId int, input todolist_app.UpdateItemInput) error {
-	return s.repo.Update(userId, itemId, input)
+func (s *TodoItemService) Move(ctx context.Context, userId, itemId int, input todolist_app.MoveItemInput) error {
+	return s.repo.Move(ctx, userId, itemId, input.ListId)
+}
+
+// Batch runs create, update and delete operations on the items of a list in one transaction.
+// Completed recurring items spawn their next occurrence within it, like single updates do.
+func (s *TodoItemService) Batch(ctx context.Context, userId, listId int, input todolist_app.BatchInput) ([]todolist_app.BatchResult, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := checkWriteAccess(s.listRepo.GetRole(ctx, userId, listId)); err != nil {
+		return nil, err
+	}
+
+	for n, op := range input.Operations {
+		if op.Op == todolist_app.BatchOpCreate {
+			item := *op.Item
+			item.ParentId = nil
+			item.SeriesId = nil
+
+			recurrence, err := normalizeRecurrence(item.Recurrence)
+			if err != nil {
+				return nil, todolist_app.NewValidationError(fmt.Sprintf("operation %d: %s", n, err))
+			}
+			item.Recurrence = recurrence
+			input.Operations[n].Item = &item
+		}
+	}
+
+	return s.repo.Batch(ctx, userId, listId, input.Operations, input.Atomic())
+}
+
+// CompleteAll marks every item of the list as done and returns how many items changed.
+// Recurring items spawn their next occurrence in the same transaction.
+func (s *TodoItemService) CompleteAll(ctx context.Context, userId, listId int) (int, error) {
+	if err := checkWriteAccess(s.listRepo.GetRole(ctx, userId, listId)); err != nil {
+		return 0, err
+	}
+
+	items, err := s.repo.SetDone(ctx, userId, listId, true)
+
+	return len(items), err
+}
+
+// UncheckAll marks every item of the list as not done and returns how many items changed.
+func (s *TodoItemService) UncheckAll(ctx context.Context, userId, listId int) (int, error) {
+	if err := checkWriteAccess(s.listRepo.GetRole(ctx, userId, listId)); err != nil {
+		return 0, err
+	}
+
+	items, err := s.repo.SetDone(ctx, userId, listId, false)
+
+	return len(items), err
+}
+
+// ClearCompleted moves the done items of the list to the trash and returns how many were removed.
+func (s *TodoItemService) ClearCompleted(ctx context.Context, userId, listId int) (int, error) {
+	if err := checkWriteAccess(s.listRepo.GetRole(ctx, userId, listId)); err != nil {
+		return 0, err
+	}
+
+	return s.repo.DeleteDone(ctx, userId, listId)
 }
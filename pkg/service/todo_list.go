@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
 	todolist_app "todolist-app"
 	"todolist-app/pkg/repository"
 )
 
+var errForbidden = todolist_app.NewForbiddenError("not enough permissions on the list")
+
 type TodoListService struct {
 	repo repository.TodoList
 }
@@ -13,26 +16,97 @@ func NewTodoListService(repo repository.TodoList) *TodoListService {
 	return &TodoListService{repo: repo}
 }
 
-func (s *TodoListService) Create(userId int, list todolist_app.TodoList) (int, error) {
-	return s.repo.Create(userId, list)
+func (s *TodoListService) Create(ctx context.Context, userId int, list todolist_app.TodoList) (int, error) {
+	return s.repo.Create(ctx, userId, list)
 }
 
-func (s *TodoListService) GetAll(userId int) ([]todolist_app.TodoList, error) {
-	return s.repo.GetAll(userId)
+func (s *TodoListService) GetAll(ctx context.Context, userId int, filter todolist_app.ListFilter) ([]todolist_app.TodoList, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	return s.repo.GetAll(ctx, userId, filter)
 }
 
-func (s *TodoListService) GetById(userId, listId int) (todolist_app.TodoList, error) {
-	return s.repo.GetById(userId, listId)
+func (s *TodoListService) GetById(ctx context.Context, userId, listId int) (todolist_app.TodoList, error) {
+	return s.repo.GetById(ctx, userId, listId)
 }
 
-func (s *TodoListService) Delete(userId, listId int) error {
-	return s.repo.Delete(userId, listId)
+func (s *TodoListService) Delete(ctx context.Context, userId, listId int) error {
+	if err := checkOwner(s.repo.GetRole(ctx, userId, listId)); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, userId, listId)
 }
 
-func (s *TodoListService) Update(userId, listId int, input todolist_app.UpdateListInput) error {
+func (s *TodoListService) Update(ctx context.Context, userId, listId int, input todolist_app.UpdateListInput) error {
 	if err := input.Validate(); err != nil {
 		return err
 	}
 
-	return s.repo.Update(userId, listId, input)
+	if err := checkWriteAccess(s.repo.GetRole(ctx, userId, listId)); err != nil {
+		return err
+	}
+
+	return s.repo.Update(ctx, userId, listId, input)
+}
+
+func (s *TodoListService) GetMembers(ctx context.Context, userId, listId int) ([]todolist_app.ListMember, error) {
+	if _, err := s.repo.GetRole(ctx, userId, listId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMembers(ctx, listId)
+}
+
+func (s *TodoListService) SaveMember(ctx context.Context, userId, listId int, input todolist_app.AddMemberInput) (int, error) {
+	if err := input.Validate(); err != nil {
+		return 0, err
+	}
+
+	if err := checkOwner(s.repo.GetRole(ctx, userId, listId)); err != nil {
+		return 0, err
+	}
+
+	// the repository refuses to demote the last owner under the lock of the list's owners
+	return s.repo.SaveMember(ctx, userId, listId, input.Username, input.Role)
+}
+
+func (s *TodoListService) DeleteMember(ctx context.Context, userId, listId, memberId int) error {
+	role, err := s.repo.GetRole(ctx, userId, listId)
+	if err != nil {
+		return err
+	}
+
+	// any member may leave a list, only owners may remove others
+	if memberId != userId && role != todolist_app.RoleOwner {
+		return errForbidden
+	}
+
+	return s.repo.DeleteMember(ctx, userId, listId, memberId)
+}
+
+func checkOwner(role todolist_app.Role, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if role != todolist_app.RoleOwner {
+		return errForbidden
+	}
+
+	return nil
+}
+
+func checkWriteAccess(role todolist_app.Role, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if !role.CanWrite() {
+		return errForbidden
+	}
+
+	return nil
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"time"
+	todolist_app "todolist-app"
+	"todolist-app/pkg/repository"
+)
+
+type TrashService struct {
+	repo repository.Trash
+}
+
+func NewTrashService(repo repository.Trash) *TrashService {
+	return &TrashService{repo: repo}
+}
+
+func (s *TrashService) GetAll(ctx context.Context, userId int) ([]todolist_app.TrashEntry, error) {
+	return s.repo.GetAll(ctx, userId)
+}
+
+func (s *TrashService) RestoreList(ctx context.Context, userId, listId int) error {
+	return s.repo.RestoreList(ctx, userId, listId)
+}
+
+func (s *TrashService) RestoreItem(ctx context.Context, userId, itemId int) error {
+	return s.repo.RestoreItem(ctx, userId, itemId)
+}
+
+// Purge permanently removes what has been in the trash for longer than retention.
+func (s *TrashService) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.Purge(ctx, time.Now().Add(-retention))
+}
+
+// RunTrashPurge purges the trash every interval until ctx is done.
+func RunTrashPurge(ctx context.Context, trash Trash, interval, retention time.Duration) {
+	if interval <= 0 || retention <= 0 {
+		logrus.Warn("trash purge is disabled, set trash.retention and trash.purge_interval to enable it")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := trash.Purge(ctx, retention)
+		if err != nil {
+			logrus.Errorf("error occured while purging trash: %s", err.Error())
+		} else if purged > 0 {
+			logrus.Infof("purged %d rows from trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package todolist_app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is the subset of an RFC 5545 RRULE supported for recurring items:
+// FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, BYDAY for weekly rules and UNTIL.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Until    *time.Time
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+func ParseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, NewValidationError(fmt.Sprintf("invalid recurrence part %q", part))
+		}
+
+		switch name {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.Freq = value
+			default:
+				return r, NewValidationError(fmt.Sprintf("unsupported recurrence frequency %q", value))
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return r, NewValidationError("recurrence interval must be a positive number")
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					return r, NewValidationError(fmt.Sprintf("invalid recurrence day %q", day))
+				}
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return r, NewValidationError(fmt.Sprintf("invalid recurrence end %q", value))
+			}
+			r.Until = &until
+		default:
+			return r, NewValidationError(fmt.Sprintf("unsupported recurrence part %q", name))
+		}
+	}
+
+	if r.Freq == "" {
+		return r, NewValidationError("recurrence frequency is required")
+	}
+
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return r, NewValidationError("recurrence days are only supported for weekly rules")
+	}
+
+	return r, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	// a date without a time ends the series after that whole day
+	if len(value) == len("20060102") {
+		day, err := time.Parse("20060102", value)
+		return day.Add(24*time.Hour - time.Nanosecond), err
+	}
+
+	return time.Parse("20060102T150405Z", value)
+}
+
+// String returns the rule in canonical RRULE form, which is how it is stored.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, name := range []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"} {
+			if r.hasDay(rruleWeekdays[name]) {
+				days = append(days, name)
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// NextAfter returns the first occurrence of the series anchored at due that is later than now,
+// so completing an item late does not spawn occurrences that are already overdue.
+// It reports false when the series has ended.
+func (r Recurrence) NextAfter(due, now time.Time) (time.Time, bool) {
+	next := r.next(due)
+	for !next.After(now) {
+		next = r.next(next)
+	}
+
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+
+	return next, true
+}
+
+// NextOccurrence returns the undone item that follows a recurring item once input completes it at now,
+// and false when the series has ended. item is the item before the update, the fields input changes are
+// copied from input. Items without a due date repeat from the time they were completed.
+func NextOccurrence(item TodoItem, input UpdateItemInput, now time.Time) (TodoItem, bool, error) {
+	rule, err := ParseRecurrence(*item.Recurrence)
+	if err != nil {
+		return TodoItem{}, false, err
+	}
+
+	if input.Title != nil {
+		item.Title = *input.Title
+	}
+	if input.Description != nil {
+		item.Description = *input.Description
+	}
+	if input.Priority != nil {
+		item.Priority = *input.Priority
+	}
+	if item.DueAt, item.RemindAt, err = input.Schedule(item.DueAt, item.RemindAt); err != nil {
+		return TodoItem{}, false, err
+	}
+
+	due := now
+	if item.DueAt != nil {
+		due = *item.DueAt
+	}
+
+	nextDue, ok := rule.NextAfter(due, now)
+	if !ok {
+		return TodoItem{}, false, nil
+	}
+
+	next := TodoItem{
+		Title:       item.Title,
+		Description: item.Description,
+		Priority:    item.Priority,
+		DueAt:       &nextDue,
+		Recurrence:  item.Recurrence,
+		SeriesId:    item.SeriesId,
+	}
+
+	if next.SeriesId == nil {
+		next.SeriesId = &item.Id
+	}
+
+	// the reminder keeps its offset from the due date
+	if item.RemindAt != nil && item.DueAt != nil {
+		remindAt := nextDue.Add(item.RemindAt.Sub(*item.DueAt))
+		next.RemindAt = &remindAt
+	}
+
+	return next, true, nil
+}
+
+func (r Recurrence) next(t time.Time) time.Time {
+	switch r.Freq {
+	case "DAILY":
+		return t.AddDate(0, 0, r.Interval)
+	case "WEEKLY":
+		if len(r.ByDay) == 0 {
+			return t.AddDate(0, 0, 7*r.Interval)
+		}
+
+		// weeks start on Monday, as with the RRULE default WKST=MO
+		weekStart := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		for day := t.AddDate(0, 0, 1); day.Before(weekStart.AddDate(0, 0, 7)); day = day.AddDate(0, 0, 1) {
+			if r.hasDay(day.Weekday()) {
+				return day
+			}
+		}
+
+		for day := weekStart.AddDate(0, 0, 7*r.Interval); ; day = day.AddDate(0, 0, 1) {
+			if r.hasDay(day.Weekday()) {
+				return day
+			}
+		}
+	case "MONTHLY":
+		// months without the day, e.g. the 31st, are skipped as RFC 5545 requires
+		for months := r.Interval; ; months += r.Interval {
+			if next := t.AddDate(0, months, 0); next.Day() == t.Day() {
+				return next
+			}
+		}
+	default:
+		for years := r.Interval; ; years += r.Interval {
+			if next := t.AddDate(years, 0, 0); next.Day() == t.Day() {
+				return next
+			}
+		}
+	}
+}
+
+func (r Recurrence) hasDay(weekday time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == weekday {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package todolist_app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	valid := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=daily;interval=1", "FREQ=DAILY"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR,MO", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"FREQ=MONTHLY;UNTIL=20240131T120000Z", "FREQ=MONTHLY;UNTIL=20240131T120000Z"},
+		{"FREQ=YEARLY;UNTIL=20240131", "FREQ=YEARLY;UNTIL=20240131T235959Z"},
+	}
+
+	for _, tc := range valid {
+		r, err := ParseRecurrence(tc.rule)
+		if err != nil {
+			t.Fatalf("ParseRecurrence(%q): %v", tc.rule, err)
+		}
+
+		if got := r.String(); got != tc.want {
+			t.Fatalf("ParseRecurrence(%q) = %q, want %q", tc.rule, got, tc.want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"FREQ",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		// COUNT is not supported, series end with UNTIL only
+		"FREQ=DAILY;COUNT=3",
+	}
+
+	for _, rule := range invalid {
+		if _, err := ParseRecurrence(rule); ErrorCodeOf(err) != CodeValidation {
+			t.Fatalf("ParseRecurrence(%q) returned %v, want a validation error", rule, err)
+		}
+	}
+}
+
+func TestRecurrenceNextAfter(t *testing.T) {
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		due  time.Time
+		// now defaults to due
+		now  time.Time
+		want time.Time
+		ok   bool
+	}{
+		{"daily", "FREQ=DAILY", at(2024, 1, 1, 9), time.Time{}, at(2024, 1, 2, 9), true},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", at(2024, 1, 1, 9), time.Time{}, at(2024, 1, 4, 9), true},
+		{"completed late", "FREQ=DAILY", at(2024, 1, 1, 9), at(2024, 1, 5, 12), at(2024, 1, 6, 9), true},
+		{"weekly", "FREQ=WEEKLY", at(2024, 1, 3, 9), time.Time{}, at(2024, 1, 10, 9), true},
+		{"weekly by day in the week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", at(2024, 1, 3, 9), time.Time{}, at(2024, 1, 5, 9), true},
+		{"weekly by day next week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", at(2024, 1, 5, 9), time.Time{}, at(2024, 1, 8, 9), true},
+		{"weekly by day interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", at(2024, 1, 3, 9), time.Time{}, at(2024, 1, 15, 9), true},
+		{"weekly sunday ends the week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", at(2024, 1, 1, 9), time.Time{}, at(2024, 1, 7, 9), true},
+		{"monthly on the 31st", "FREQ=MONTHLY", at(2024, 1, 31, 9), time.Time{}, at(2024, 3, 31, 9), true},
+		{"monthly on the 31st skips short months", "FREQ=MONTHLY", at(2024, 3, 31, 9), time.Time{}, at(2024, 5, 31, 9), true},
+		{"monthly on feb 29", "FREQ=MONTHLY", at(2024, 2, 29, 9), time.Time{}, at(2024, 3, 29, 9), true},
+		{"monthly interval", "FREQ=MONTHLY;INTERVAL=2", at(2024, 1, 15, 9), time.Time{}, at(2024, 3, 15, 9), true},
+		{"yearly on feb 29", "FREQ=YEARLY", at(2024, 2, 29, 9), time.Time{}, at(2028, 2, 29, 9), true},
+		{"until the next occurrence", "FREQ=DAILY;UNTIL=20240102T090000Z", at(2024, 1, 1, 9), time.Time{}, at(2024, 1, 2, 9), true},
+		{"until before the next occurrence", "FREQ=DAILY;UNTIL=20240102T085959Z", at(2024, 1, 1, 9), time.Time{}, time.Time{}, false},
+		{"until the whole day", "FREQ=DAILY;UNTIL=20240102", at(2024, 1, 1, 23), time.Time{}, at(2024, 1, 2, 23), true},
+		{"until the day before", "FREQ=DAILY;UNTIL=20240102", at(2024, 1, 2, 9), time.Time{}, time.Time{}, false},
+	}
+
+	for _, tc := range tests {
+		r, err := ParseRecurrence(tc.rule)
+		if err != nil {
+			t.Fatalf("%s: ParseRecurrence(%q): %v", tc.name, tc.rule, err)
+		}
+
+		now := tc.now
+		if now.IsZero() {
+			now = tc.due
+		}
+
+		got, ok := r.NextAfter(tc.due, now)
+		if ok != tc.ok || !got.Equal(tc.want) {
+			t.Fatalf("%s: NextAfter = %v, %v, want %v, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	rule := "FREQ=WEEKLY"
+	due := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+	remindAt := due.Add(-time.Hour)
+	item := TodoItem{Id: 7, Title: "Report", Priority: PriorityHigh, DueAt: &due, RemindAt: &remindAt, Recurrence: &rule}
+
+	title := "Weekly report"
+	next, ok, err := NextOccurrence(item, UpdateItemInput{Title: &title}, due)
+	if err != nil || !ok {
+		t.Fatalf("NextOccurrence = %v, %v", ok, err)
+	}
+
+	wantDue, wantRemind := due.AddDate(0, 0, 7), due.AddDate(0, 0, 7).Add(-time.Hour)
+	switch {
+	case next.Title != title || next.Priority != PriorityHigh || next.Done:
+		t.Fatalf("next occurrence is %+v", next)
+	case next.SeriesId == nil || *next.SeriesId != item.Id:
+		t.Fatalf("next occurrence has series id %v, want %d", next.SeriesId, item.Id)
+	case !next.DueAt.Equal(wantDue) || !next.RemindAt.Equal(wantRemind):
+		t.Fatalf("next occurrence is due %v with a reminder at %v, want %v and %v", next.DueAt, next.RemindAt, wantDue, wantRemind)
+	}
+
+	// without a due date the series repeats from the completion
+	completed := time.Date(2024, 1, 5, 18, 0, 0, 0, time.UTC)
+	next, ok, err = NextOccurrence(TodoItem{Id: 8, Recurrence: &rule}, UpdateItemInput{}, completed)
+	if err != nil || !ok || !next.DueAt.Equal(completed.AddDate(0, 0, 7)) {
+		t.Fatalf("NextOccurrence without a due date = %v, %v, %v", next.DueAt, ok, err)
+	}
+
+	ended := "FREQ=DAILY;UNTIL=20240103"
+	if _, ok, err := NextOccurrence(TodoItem{Id: 9, DueAt: &due, Recurrence: &ended}, UpdateItemInput{}, due); ok || err != nil {
+		t.Fatalf("NextOccurrence after the end of the series = %v, %v", ok, err)
+	}
+}
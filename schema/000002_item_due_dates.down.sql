@@ -0,0 +1,5 @@
+DROP INDEX todo_items_due_at_idx;
+
+ALTER TABLE todo_items
+    DROP COLUMN due_at,
+    DROP COLUMN remind_at;
@@ -0,0 +1,5 @@
+ALTER TABLE todo_items
+    ADD COLUMN due_at    timestamp with time zone,
+    ADD COLUMN remind_at timestamp with time zone;
+
+CREATE INDEX todo_items_due_at_idx ON todo_items (due_at);
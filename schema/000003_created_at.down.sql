@@ -0,0 +1,5 @@
+ALTER TABLE todo_items
+    DROP COLUMN created_at;
+
+ALTER TABLE todo_lists
+    DROP COLUMN created_at;
@@ -0,0 +1,13 @@
+CREATE TABLE refresh_tokens
+(
+    id         serial                                      not null unique,
+    user_id    int references users (id) on delete cascade not null,
+    session_id varchar(64)                                 not null,
+    token_hash varchar(64)                                 not null unique,
+    expires_at timestamp with time zone                    not null,
+    created_at timestamp with time zone                    not null default now(),
+    used_at    timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id);
@@ -0,0 +1,9 @@
+ALTER TABLE lists_items
+    ADD COLUMN position double precision not null default 0;
+
+UPDATE lists_items li
+SET position = ranked.rank * 1024
+FROM (SELECT id, row_number() OVER (PARTITION BY list_id ORDER BY item_id) AS rank FROM lists_items) ranked
+WHERE li.id = ranked.id;
+
+CREATE INDEX lists_items_list_id_position_idx ON lists_items (list_id, position);
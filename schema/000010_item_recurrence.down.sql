@@ -0,0 +1,5 @@
+DROP INDEX todo_items_series_due_at_idx;
+
+ALTER TABLE todo_items
+    DROP COLUMN series_id,
+    DROP COLUMN recurrence;
@@ -0,0 +1,6 @@
+ALTER TABLE todo_items
+    ADD COLUMN recurrence varchar(255),
+    ADD COLUMN series_id  int;
+
+-- an occurrence is spawned at most once, even if an item is completed twice
+CREATE UNIQUE INDEX todo_items_series_due_at_idx ON todo_items (series_id, due_at);
@@ -0,0 +1,15 @@
+ALTER TABLE todo_lists
+    ADD COLUMN search tsvector generated always as (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) stored;
+
+ALTER TABLE todo_items
+    ADD COLUMN search tsvector generated always as (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) stored;
+
+CREATE INDEX todo_lists_search_idx ON todo_lists USING gin (search);
+
+CREATE INDEX todo_items_search_idx ON todo_items USING gin (search);
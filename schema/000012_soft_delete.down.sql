@@ -0,0 +1,14 @@
+DROP INDEX todo_items_deleted_at_idx;
+
+DROP INDEX todo_lists_deleted_at_idx;
+
+-- trashed rows would reappear without the column
+DELETE FROM todo_items WHERE deleted_at IS NOT NULL;
+
+DELETE FROM todo_lists WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE todo_items
+    DROP COLUMN deleted_at;
+
+ALTER TABLE todo_lists
+    DROP COLUMN deleted_at;
@@ -0,0 +1,48 @@
+ALTER TABLE todo_lists
+    ADD COLUMN updated_at timestamp with time zone;
+
+ALTER TABLE todo_items
+    ADD COLUMN updated_at   timestamp with time zone,
+    ADD COLUMN completed_at timestamp with time zone;
+
+ALTER TABLE labels
+    ADD COLUMN updated_at timestamp with time zone;
+
+-- the activity log knows when rows last changed, older rows fall back to their creation time
+UPDATE todo_lists tl
+SET updated_at = COALESCE((SELECT max(a.created_at)
+                           FROM activity a
+                           WHERE a.list_id = tl.id
+                             AND a.entity = 'list'
+                             AND a.action = 'updated'), tl.created_at);
+
+UPDATE todo_items ti
+SET updated_at = COALESCE((SELECT max(a.created_at)
+                           FROM activity a
+                           WHERE a.item_id = ti.id
+                             AND a.action IN ('updated', 'series_updated', 'series_stopped', 'moved')), ti.created_at);
+
+UPDATE todo_items ti
+SET completed_at = COALESCE((SELECT max(a.created_at)
+                             FROM activity a
+                             WHERE a.item_id = ti.id
+                               AND a.action = 'updated'
+                               AND a.changes -> 'done' ->> 'after' = 'true'), ti.updated_at)
+WHERE ti.done;
+
+UPDATE labels
+SET updated_at = created_at;
+
+ALTER TABLE todo_lists
+    ALTER COLUMN updated_at SET NOT NULL,
+    ALTER COLUMN updated_at SET DEFAULT now();
+
+ALTER TABLE todo_items
+    ALTER COLUMN updated_at SET NOT NULL,
+    ALTER COLUMN updated_at SET DEFAULT now();
+
+ALTER TABLE labels
+    ALTER COLUMN updated_at SET NOT NULL,
+    ALTER COLUMN updated_at SET DEFAULT now();
+
+CREATE INDEX todo_items_completed_at_idx ON todo_items (completed_at) WHERE completed_at IS NOT NULL;
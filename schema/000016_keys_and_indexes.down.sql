@@ -0,0 +1,105 @@
+DROP TRIGGER todo_lists_delete_items ON todo_lists;
+
+DROP FUNCTION todo_lists_delete_items();
+
+DROP INDEX activity_actor_id_idx;
+
+DROP INDEX refresh_tokens_user_id_idx;
+
+DROP INDEX lists_items_item_id_idx;
+
+DROP INDEX users_lists_list_id_idx;
+
+ALTER TABLE lists_items
+    DROP CONSTRAINT lists_items_list_id_item_id_key;
+
+ALTER TABLE users_lists
+    DROP CONSTRAINT users_lists_user_id_list_id_key;
+
+ALTER TABLE users_lists
+    DROP CONSTRAINT users_lists_user_id_fkey,
+    DROP CONSTRAINT users_lists_list_id_fkey;
+
+ALTER TABLE lists_items
+    DROP CONSTRAINT lists_items_item_id_fkey,
+    DROP CONSTRAINT lists_items_list_id_fkey;
+
+ALTER TABLE todo_items
+    DROP CONSTRAINT todo_items_parent_id_fkey;
+
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT refresh_tokens_user_id_fkey;
+
+ALTER TABLE labels
+    DROP CONSTRAINT labels_user_id_fkey;
+
+ALTER TABLE items_labels
+    DROP CONSTRAINT items_labels_item_id_fkey,
+    DROP CONSTRAINT items_labels_label_id_fkey;
+
+ALTER TABLE activity
+    DROP CONSTRAINT activity_list_id_fkey,
+    DROP CONSTRAINT activity_item_id_fkey,
+    DROP CONSTRAINT activity_actor_id_fkey;
+
+ALTER TABLE users
+    DROP CONSTRAINT users_pkey,
+    ADD CONSTRAINT users_id_key UNIQUE (id);
+
+ALTER TABLE todo_lists
+    DROP CONSTRAINT todo_lists_pkey,
+    ADD CONSTRAINT todo_lists_id_key UNIQUE (id);
+
+ALTER TABLE users_lists
+    DROP CONSTRAINT users_lists_pkey,
+    ADD CONSTRAINT users_lists_id_key UNIQUE (id);
+
+ALTER TABLE todo_items
+    DROP CONSTRAINT todo_items_pkey,
+    ADD CONSTRAINT todo_items_id_key UNIQUE (id);
+
+ALTER TABLE lists_items
+    DROP CONSTRAINT lists_items_pkey,
+    ADD CONSTRAINT lists_items_id_key UNIQUE (id);
+
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT refresh_tokens_pkey,
+    ADD CONSTRAINT refresh_tokens_id_key UNIQUE (id);
+
+ALTER TABLE labels
+    DROP CONSTRAINT labels_pkey,
+    ADD CONSTRAINT labels_id_key UNIQUE (id);
+
+ALTER TABLE items_labels
+    DROP CONSTRAINT items_labels_pkey,
+    ADD CONSTRAINT items_labels_id_key UNIQUE (id);
+
+ALTER TABLE activity
+    DROP CONSTRAINT activity_pkey,
+    ADD CONSTRAINT activity_id_key UNIQUE (id);
+
+ALTER TABLE users_lists
+    ADD CONSTRAINT users_lists_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT users_lists_list_id_fkey FOREIGN KEY (list_id) REFERENCES todo_lists (id) ON DELETE CASCADE;
+
+ALTER TABLE lists_items
+    ADD CONSTRAINT lists_items_item_id_fkey FOREIGN KEY (item_id) REFERENCES todo_items (id) ON DELETE CASCADE,
+    ADD CONSTRAINT lists_items_list_id_fkey FOREIGN KEY (list_id) REFERENCES todo_lists (id) ON DELETE CASCADE;
+
+ALTER TABLE todo_items
+    ADD CONSTRAINT todo_items_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES todo_items (id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE labels
+    ADD CONSTRAINT labels_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE items_labels
+    ADD CONSTRAINT items_labels_item_id_fkey FOREIGN KEY (item_id) REFERENCES todo_items (id) ON DELETE CASCADE,
+    ADD CONSTRAINT items_labels_label_id_fkey FOREIGN KEY (label_id) REFERENCES labels (id) ON DELETE CASCADE;
+
+ALTER TABLE activity
+    ADD CONSTRAINT activity_list_id_fkey FOREIGN KEY (list_id) REFERENCES todo_lists (id) ON DELETE CASCADE,
+    ADD CONSTRAINT activity_item_id_fkey FOREIGN KEY (item_id) REFERENCES todo_items (id) ON DELETE SET NULL,
+    ADD CONSTRAINT activity_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
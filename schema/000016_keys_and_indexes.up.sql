@@ -0,0 +1,142 @@
+-- the ids were serial unique columns and the foreign keys depend on their unique constraints,
+-- so the foreign keys are dropped and created again on top of the primary keys
+ALTER TABLE users_lists
+    DROP CONSTRAINT users_lists_user_id_fkey,
+    DROP CONSTRAINT users_lists_list_id_fkey;
+
+ALTER TABLE lists_items
+    DROP CONSTRAINT lists_items_item_id_fkey,
+    DROP CONSTRAINT lists_items_list_id_fkey;
+
+ALTER TABLE todo_items
+    DROP CONSTRAINT todo_items_parent_id_fkey;
+
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT refresh_tokens_user_id_fkey;
+
+ALTER TABLE labels
+    DROP CONSTRAINT labels_user_id_fkey;
+
+ALTER TABLE items_labels
+    DROP CONSTRAINT items_labels_item_id_fkey,
+    DROP CONSTRAINT items_labels_label_id_fkey;
+
+ALTER TABLE activity
+    DROP CONSTRAINT activity_list_id_fkey,
+    DROP CONSTRAINT activity_item_id_fkey,
+    DROP CONSTRAINT activity_actor_id_fkey;
+
+ALTER TABLE users
+    DROP CONSTRAINT users_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE todo_lists
+    DROP CONSTRAINT todo_lists_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE users_lists
+    DROP CONSTRAINT users_lists_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE todo_items
+    DROP CONSTRAINT todo_items_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE lists_items
+    DROP CONSTRAINT lists_items_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT refresh_tokens_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE labels
+    DROP CONSTRAINT labels_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE items_labels
+    DROP CONSTRAINT items_labels_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE activity
+    DROP CONSTRAINT activity_id_key,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE users_lists
+    ADD CONSTRAINT users_lists_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT users_lists_list_id_fkey FOREIGN KEY (list_id) REFERENCES todo_lists (id) ON DELETE CASCADE;
+
+ALTER TABLE lists_items
+    ADD CONSTRAINT lists_items_item_id_fkey FOREIGN KEY (item_id) REFERENCES todo_items (id) ON DELETE CASCADE,
+    ADD CONSTRAINT lists_items_list_id_fkey FOREIGN KEY (list_id) REFERENCES todo_lists (id) ON DELETE CASCADE;
+
+ALTER TABLE todo_items
+    ADD CONSTRAINT todo_items_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES todo_items (id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE labels
+    ADD CONSTRAINT labels_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE items_labels
+    ADD CONSTRAINT items_labels_item_id_fkey FOREIGN KEY (item_id) REFERENCES todo_items (id) ON DELETE CASCADE,
+    ADD CONSTRAINT items_labels_label_id_fkey FOREIGN KEY (label_id) REFERENCES labels (id) ON DELETE CASCADE;
+
+ALTER TABLE activity
+    ADD CONSTRAINT activity_list_id_fkey FOREIGN KEY (list_id) REFERENCES todo_lists (id) ON DELETE CASCADE,
+    ADD CONSTRAINT activity_item_id_fkey FOREIGN KEY (item_id) REFERENCES todo_items (id) ON DELETE SET NULL,
+    ADD CONSTRAINT activity_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+
+-- a user is a member of a list and an item is in a list once. Of duplicate memberships the one with
+-- the strongest role is kept, the oldest of equal ones, so no list loses its owner
+DELETE
+FROM users_lists
+WHERE id IN (SELECT id
+             FROM (SELECT id,
+                          row_number() OVER (PARTITION BY user_id, list_id
+                              ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, id) AS n
+                   FROM users_lists) ranked
+             WHERE n > 1);
+
+-- of duplicate items the oldest row is kept
+DELETE
+FROM lists_items li USING lists_items dup
+WHERE dup.list_id = li.list_id
+  AND dup.item_id = li.item_id
+  AND dup.id < li.id;
+
+ALTER TABLE users_lists
+    ADD CONSTRAINT users_lists_user_id_list_id_key UNIQUE (user_id, list_id);
+
+ALTER TABLE lists_items
+    ADD CONSTRAINT lists_items_list_id_item_id_key UNIQUE (list_id, item_id);
+
+-- the unique constraints cover the lookups by user and by list, these cover the other direction
+CREATE INDEX users_lists_list_id_idx ON users_lists (list_id, user_id);
+
+CREATE INDEX lists_items_item_id_idx ON lists_items (item_id, list_id);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE INDEX activity_actor_id_idx ON activity (actor_id);
+
+-- items reach their list through lists_items only, so the cascade from a removed list stopped there
+-- and left the items behind
+DELETE
+FROM todo_items ti
+WHERE NOT EXISTS (SELECT 1 FROM lists_items li WHERE li.item_id = ti.id);
+
+CREATE FUNCTION todo_lists_delete_items() RETURNS trigger AS
+$$
+BEGIN
+    DELETE FROM todo_items WHERE id IN (SELECT item_id FROM lists_items WHERE list_id = OLD.id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todo_lists_delete_items
+    BEFORE DELETE
+    ON todo_lists
+    FOR EACH ROW
+EXECUTE FUNCTION todo_lists_delete_items();
@@ -0,0 +1,19 @@
+// Package schema embeds the database migrations, so the binary can apply them itself.
+// The files are named like golang-migrate expects: <version>_<name>.up.sql and .down.sql.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Postgres holds the migrations of the Postgres database.
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+//go:embed sqlite/*.sql
+var sqlite embed.FS
+
+// SQLite holds the migrations ported to SQLite, at the root of the file system like Postgres.
+var SQLite, _ = fs.Sub(sqlite, "sqlite")
@@ -0,0 +1,9 @@
+DROP TABLE lists_items;
+
+DROP TABLE users_lists;
+
+DROP TABLE todo_lists;
+
+DROP TABLE users;
+
+DROP TABLE todo_items;
@@ -0,0 +1,39 @@
+-- SQLite has no length limits or timestamp type: lengths are checked, and the repositories store
+-- timestamps as UTC text that sorts like the times it represents
+CREATE TABLE users
+(
+    id            integer primary key autoincrement,
+    name          varchar(255) not null check (length(name) <= 255),
+    username      varchar(255) not null unique check (length(username) <= 255),
+    password_hash varchar(255) not null check (length(password_hash) <= 255)
+);
+
+CREATE TABLE todo_lists
+(
+    id          integer primary key autoincrement,
+    title       varchar(255) not null check (length(title) <= 255),
+    description varchar(255) check (length(description) <= 255)
+);
+
+CREATE TABLE users_lists
+(
+    id      integer primary key autoincrement,
+    user_id int references users (id) on delete cascade      not null,
+    list_id int references todo_lists (id) on delete cascade not null
+);
+
+CREATE TABLE todo_items
+(
+    id          integer primary key autoincrement,
+    title       varchar(255) not null check (length(title) <= 255),
+    description varchar(255) check (length(description) <= 255),
+    done        boolean      not null default false
+);
+
+
+CREATE TABLE lists_items
+(
+    id      integer primary key autoincrement,
+    item_id int references todo_items (id) on delete cascade not null,
+    list_id int references todo_lists (id) on delete cascade not null
+);
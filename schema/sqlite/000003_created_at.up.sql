@@ -0,0 +1,12 @@
+-- an added column needs a constant default, the repositories always set the creation time
+ALTER TABLE todo_lists
+    ADD COLUMN created_at timestamp not null default '1970-01-01 00:00:00+00:00';
+
+ALTER TABLE todo_items
+    ADD COLUMN created_at timestamp not null default '1970-01-01 00:00:00+00:00';
+
+UPDATE todo_lists
+SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
+
+UPDATE todo_items
+SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
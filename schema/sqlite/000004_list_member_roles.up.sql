@@ -0,0 +1,2 @@
+ALTER TABLE users_lists
+    ADD COLUMN role varchar(16) not null default 'owner' check (role in ('owner', 'editor', 'viewer'));
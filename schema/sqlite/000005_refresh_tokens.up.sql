@@ -0,0 +1,13 @@
+CREATE TABLE refresh_tokens
+(
+    id         integer primary key autoincrement,
+    user_id    int references users (id) on delete cascade not null,
+    session_id varchar(64)                                 not null check (length(session_id) <= 64),
+    token_hash varchar(64)                                 not null unique check (length(token_hash) <= 64),
+    expires_at timestamp                                   not null,
+    created_at timestamp                                   not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    used_at    timestamp,
+    revoked_at timestamp
+);
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id);
@@ -0,0 +1,10 @@
+ALTER TABLE lists_items
+    ADD COLUMN position double precision not null default 0;
+
+UPDATE lists_items
+SET position = 1024 * (SELECT count(*)
+                       FROM lists_items ranked
+                       WHERE ranked.list_id = lists_items.list_id
+                         AND ranked.item_id <= lists_items.item_id);
+
+CREATE INDEX lists_items_list_id_position_idx ON lists_items (list_id, position);
@@ -0,0 +1,6 @@
+DROP TRIGGER todo_items_delete_subtasks;
+
+DROP INDEX todo_items_parent_id_idx;
+
+ALTER TABLE todo_items
+    DROP COLUMN parent_id;
@@ -0,0 +1,13 @@
+-- SQLite cannot drop a column that references another table, so parent_id has no foreign key
+-- and the subtasks of a removed item are removed by a trigger instead of a cascade
+ALTER TABLE todo_items
+    ADD COLUMN parent_id int;
+
+CREATE INDEX todo_items_parent_id_idx ON todo_items (parent_id);
+
+CREATE TRIGGER todo_items_delete_subtasks
+    AFTER DELETE
+    ON todo_items
+BEGIN
+    DELETE FROM todo_items WHERE parent_id = OLD.id;
+END;
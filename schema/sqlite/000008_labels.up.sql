@@ -0,0 +1,19 @@
+CREATE TABLE labels
+(
+    id         integer primary key autoincrement,
+    user_id    int references users (id) on delete cascade not null,
+    name       varchar(255)                                not null check (length(name) <= 255),
+    color      varchar(16)                                 not null default '' check (length(color) <= 16),
+    created_at timestamp                                   not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    unique (user_id, name)
+);
+
+CREATE TABLE items_labels
+(
+    id       integer primary key autoincrement,
+    item_id  int references todo_items (id) on delete cascade not null,
+    label_id int references labels (id) on delete cascade     not null,
+    unique (item_id, label_id)
+);
+
+CREATE INDEX items_labels_label_id_idx ON items_labels (label_id);
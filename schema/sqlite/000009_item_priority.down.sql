@@ -0,0 +1,4 @@
+DROP INDEX todo_items_priority_idx;
+
+ALTER TABLE todo_items
+    DROP COLUMN priority;
@@ -0,0 +1,4 @@
+ALTER TABLE todo_items
+    ADD COLUMN priority smallint not null default 0 check (priority BETWEEN 0 AND 4);
+
+CREATE INDEX todo_items_priority_idx ON todo_items (priority);
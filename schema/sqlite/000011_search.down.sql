@@ -0,0 +1 @@
+-- nothing to undo, see 000011_search.up.sql
@@ -0,0 +1,2 @@
+-- there is no search column in SQLite: titles and descriptions are matched with LIKE and ranked
+-- by the repository, see SearchSQLite
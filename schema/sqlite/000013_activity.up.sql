@@ -0,0 +1,15 @@
+CREATE TABLE activity
+(
+    id         integer primary key autoincrement,
+    list_id    int references todo_lists (id) on delete cascade not null,
+    item_id    int references todo_items (id) on delete set null,
+    actor_id   int references users (id) on delete set null,
+    entity     varchar(16)                                      not null,
+    action     varchar(32)                                      not null,
+    changes    text                                             not null default '{}',
+    created_at timestamp                                        not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX activity_list_id_idx ON activity (list_id, id);
+
+CREATE INDEX activity_item_id_idx ON activity (item_id, id);
@@ -0,0 +1,13 @@
+DROP INDEX todo_items_completed_at_idx;
+
+ALTER TABLE labels
+    DROP COLUMN updated_at;
+
+ALTER TABLE todo_items
+    DROP COLUMN completed_at;
+
+ALTER TABLE todo_items
+    DROP COLUMN updated_at;
+
+ALTER TABLE todo_lists
+    DROP COLUMN updated_at;
@@ -0,0 +1,40 @@
+-- an added column needs a constant default, the repositories always set the update time
+ALTER TABLE todo_lists
+    ADD COLUMN updated_at timestamp not null default '1970-01-01 00:00:00+00:00';
+
+ALTER TABLE todo_items
+    ADD COLUMN updated_at timestamp not null default '1970-01-01 00:00:00+00:00';
+
+ALTER TABLE todo_items
+    ADD COLUMN completed_at timestamp;
+
+ALTER TABLE labels
+    ADD COLUMN updated_at timestamp not null default '1970-01-01 00:00:00+00:00';
+
+-- the activity log knows when rows last changed, older rows fall back to their creation time
+UPDATE todo_lists
+SET updated_at = COALESCE((SELECT max(a.created_at)
+                           FROM activity a
+                           WHERE a.list_id = todo_lists.id
+                             AND a.entity = 'list'
+                             AND a.action = 'updated'), todo_lists.created_at);
+
+UPDATE todo_items
+SET updated_at = COALESCE((SELECT max(a.created_at)
+                           FROM activity a
+                           WHERE a.item_id = todo_items.id
+                             AND a.action IN ('updated', 'series_updated', 'series_stopped', 'moved')),
+                          todo_items.created_at);
+
+UPDATE todo_items
+SET completed_at = COALESCE((SELECT max(a.created_at)
+                             FROM activity a
+                             WHERE a.item_id = todo_items.id
+                               AND a.action = 'updated'
+                               AND json_extract(a.changes, '$.done.after') = true), todo_items.updated_at)
+WHERE done;
+
+UPDATE labels
+SET updated_at = created_at;
+
+CREATE INDEX todo_items_completed_at_idx ON todo_items (completed_at) WHERE completed_at IS NOT NULL;
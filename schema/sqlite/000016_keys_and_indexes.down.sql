@@ -0,0 +1,13 @@
+DROP TRIGGER todo_lists_delete_items;
+
+DROP INDEX activity_actor_id_idx;
+
+DROP INDEX refresh_tokens_user_id_idx;
+
+DROP INDEX lists_items_item_id_idx;
+
+DROP INDEX users_lists_list_id_idx;
+
+DROP INDEX lists_items_list_id_item_id_key;
+
+DROP INDEX users_lists_user_id_list_id_key;
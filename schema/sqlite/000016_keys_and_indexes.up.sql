@@ -0,0 +1,42 @@
+-- the ids are primary keys from the start here, and SQLite adds constraints to a table as unique indexes only
+-- of duplicate memberships the one with the strongest role is kept, the oldest of equal ones,
+-- so no list loses its owner
+DELETE
+FROM users_lists
+WHERE id IN (SELECT id
+             FROM (SELECT id,
+                          row_number() OVER (PARTITION BY user_id, list_id
+                              ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, id) AS n
+                   FROM users_lists) ranked
+             WHERE n > 1);
+
+DELETE
+FROM lists_items
+WHERE EXISTS (SELECT 1
+              FROM lists_items dup
+              WHERE dup.list_id = lists_items.list_id
+                AND dup.item_id = lists_items.item_id
+                AND dup.id < lists_items.id);
+
+CREATE UNIQUE INDEX users_lists_user_id_list_id_key ON users_lists (user_id, list_id);
+
+CREATE UNIQUE INDEX lists_items_list_id_item_id_key ON lists_items (list_id, item_id);
+
+CREATE INDEX users_lists_list_id_idx ON users_lists (list_id, user_id);
+
+CREATE INDEX lists_items_item_id_idx ON lists_items (item_id, list_id);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE INDEX activity_actor_id_idx ON activity (actor_id);
+
+DELETE
+FROM todo_items
+WHERE NOT EXISTS (SELECT 1 FROM lists_items li WHERE li.item_id = todo_items.id);
+
+CREATE TRIGGER todo_lists_delete_items
+    BEFORE DELETE
+    ON todo_lists
+BEGIN
+    DELETE FROM todo_items WHERE id IN (SELECT item_id FROM lists_items WHERE list_id = OLD.id);
+END;
@@ -0,0 +1,39 @@
+package todolist_app
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	SearchHitList = "list"
+	SearchHitItem = "item"
+)
+
+// SearchHit is a list or an item matching a search. Title and Snippet are HTML: the text is escaped
+// and the matched words are wrapped in <mark> tags.
+type SearchHit struct {
+	Kind    string  `json:"kind" db:"kind"`
+	Id      int     `json:"id" db:"id"`
+	ListId  int     `json:"list_id" db:"list_id"`
+	Title   string  `json:"title" db:"title"`
+	Snippet string  `json:"snippet" db:"snippet"`
+	Rank    float64 `json:"rank" db:"rank"`
+}
+
+type SearchInput struct {
+	Q     string `form:"q"`
+	Limit int    `form:"limit"`
+}
+
+func (i SearchInput) Validate() error {
+	if strings.TrimSpace(i.Q) == "" {
+		return NewValidationError("search query is required")
+	}
+
+	if i.Limit < 0 || i.Limit > MaxPageLimit {
+		return NewValidationError(fmt.Sprintf("limit must be between 1 and %d, or 0 for the default", MaxPageLimit))
+	}
+
+	return nil
+}
@@ -2,25 +2,44 @@ package todolist_app
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
 )
 
 type Server struct {
 	httpServer *http.Server
+	cancel     context.CancelFunc
 }
 
-func (s *Server) Run(port string, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
-		MaxHeaderBytes: 1 << 20,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+// NewServer prepares the server before it runs, so Shutdown can be called at any time
+// from another goroutine than the one in Run.
+func NewServer(port string, handler http.Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:           ":" + port,
+			Handler:        handler,
+			MaxHeaderBytes: 1 << 20,
+			ReadTimeout:    10 * time.Second,
+			WriteTimeout:   10 * time.Second,
+			BaseContext:    func(net.Listener) context.Context { return ctx },
+		},
+		cancel: cancel,
 	}
+}
+
+// Run serves until the server fails or is shut down, in which case it returns http.ErrServerClosed.
+func (s *Server) Run() error {
 	return s.httpServer.ListenAndServe()
 }
 
+// Shutdown waits for the running requests until ctx is done and then cancels the ones left,
+// together with their queries.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	s.cancel()
+
+	return err
 }
@@ -1,40 +1,153 @@
 package todolist_app
 
-import "errors"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type TodoList struct {
-	Id          int    `json:"id" db:"id"`
-	Title       string `json:"title" db:"title" binding:"required"`
-	Description string `json:"description" db:"description"`
+	Id          int       `json:"id" db:"id"`
+	Title       string    `json:"title" db:"title" binding:"required"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Role        Role      `json:"role,omitempty" db:"role"`
+	Version     int       `json:"version" db:"version"`
+}
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleOwner || r == RoleEditor || r == RoleViewer
+}
+
+func (r Role) CanWrite() bool {
+	return r == RoleOwner || r == RoleEditor
 }
 
 type UserList struct {
 	Id     int
 	UserId int
 	ListId int
+	Role   Role
+}
+
+type ListMember struct {
+	UserId   int    `json:"user_id" db:"user_id"`
+	Name     string `json:"name" db:"name"`
+	Username string `json:"username" db:"username"`
+	Role     Role   `json:"role" db:"role"`
+}
+
+type AddMemberInput struct {
+	Username string `json:"username" binding:"required"`
+	Role     Role   `json:"role" binding:"required"`
+}
+
+func (i AddMemberInput) Validate() error {
+	if !i.Role.Valid() {
+		return NewValidationError(fmt.Sprintf("unknown role %q", i.Role))
+	}
+
+	return nil
 }
 
 type TodoItem struct {
-	Id          int    `json:"id" db:"id"`
-	Title       string `json:"title" db:"title" binding:"required"`
-	Description string `json:"description" db:"description"`
-	Done        bool   `json:"done" db:"done"`
+	Id          int        `json:"id" db:"id"`
+	ListId      int        `json:"list_id" db:"list_id"`
+	ParentId    *int       `json:"parent_id" db:"parent_id"`
+	Title       string     `json:"title" db:"title" binding:"required"`
+	Description string     `json:"description" db:"description"`
+	Done        bool       `json:"done" db:"done"`
+	DueAt       *time.Time `json:"due_at" db:"due_at"`
+	RemindAt    *time.Time `json:"remind_at" db:"remind_at"`
+	Position    float64    `json:"position" db:"position"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+	Progress    Progress   `json:"progress" db:"progress"`
+	Priority    Priority   `json:"priority" db:"priority"`
+	Recurrence  *string    `json:"recurrence" db:"recurrence"`
+	SeriesId    *int       `json:"series_id" db:"series_id"`
+	Version     int        `json:"version" db:"version"`
+}
+
+// Progress counts the done subtasks of an item.
+type Progress struct {
+	Done  int `json:"done" db:"done"`
+	Total int `json:"total" db:"total"`
+}
+
+// Priority is stored as a number so items sort by it, and is named in JSON.
+type Priority int16
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityUrgent
+}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("Priority(%d)", int16(p))
+	}
+
+	return priorityNames[p]
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	for i, n := range priorityNames {
+		if n == name {
+			*p = Priority(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown priority %q", name)
 }
 
 type ListItem struct {
-	Id     int
-	ListId int
-	ItemId int
+	Id       int
+	ListId   int
+	ItemId   int
+	Position float64
 }
 
 type UpdateListInput struct {
 	Title       *string `json:"title"`
 	Description *string `json:"description"`
+
+	// Version is the version the update is based on, taken from the If-Match header.
+	Version *int `json:"-"`
 }
 
 func (i UpdateListInput) Validate() error {
 	if i.Title == nil && i.Description == nil {
-		return errors.New("update structure has no values")
+		return NewValidationError("update structure has no values")
 	}
 
 	return nil
@@ -44,12 +157,202 @@ type UpdateItemInput struct {
 	Title       *string `json:"title"`
 	Description *string `json:"description"`
 	Done        *bool   `json:"done"`
+	// DueAt and RemindAt are cleared by an explicit null.
+	DueAt    *time.Time `json:"due_at"`
+	RemindAt *time.Time `json:"remind_at"`
+	Priority *Priority  `json:"priority"`
+
+	// ClearDueAt and ClearRemindAt are set when the JSON input has null for the field.
+	ClearDueAt    bool `json:"-"`
+	ClearRemindAt bool `json:"-"`
+
+	// Version is the version the update is based on, taken from the If-Match header.
+	Version *int `json:"-"`
+}
+
+// UnmarshalJSON tells a due_at or remind_at set to null, which clears it, apart from one left out.
+func (i *UpdateItemInput) UnmarshalJSON(data []byte) error {
+	type plain UpdateItemInput
+	var input struct {
+		plain
+		DueAt    json.RawMessage `json:"due_at"`
+		RemindAt json.RawMessage `json:"remind_at"`
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+
+	*i = UpdateItemInput(input.plain)
+
+	var err error
+	if i.DueAt, i.ClearDueAt, err = unmarshalNullableTime(input.DueAt); err != nil {
+		return err
+	}
+
+	i.RemindAt, i.ClearRemindAt, err = unmarshalNullableTime(input.RemindAt)
+
+	return err
+}
+
+func unmarshalNullableTime(data json.RawMessage) (*time.Time, bool, error) {
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+
+	if string(data) == "null" {
+		return nil, true, nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, false, err
+	}
+
+	return &t, false, nil
 }
 
 func (i UpdateItemInput) Validate() error {
-	if i.Title == nil && i.Description == nil && i.Done == nil {
-		return errors.New("update structure has no values")
+	if i.Title == nil && i.Description == nil && i.Done == nil && i.DueAt == nil && i.RemindAt == nil &&
+		i.Priority == nil && !i.ClearDueAt && !i.ClearRemindAt {
+		return NewValidationError("update structure has no values")
+	}
+
+	if i.DueAt != nil && i.RemindAt != nil && i.RemindAt.After(*i.DueAt) {
+		return errRemindAfterDue
+	}
+
+	return nil
+}
+
+var errRemindAfterDue = NewValidationError("remind_at must not be after due_at")
+
+// Schedule returns the due and reminder times of an item that has dueAt and remindAt once the update
+// is applied, and checks the reminder still comes before the due time.
+func (i UpdateItemInput) Schedule(dueAt, remindAt *time.Time) (*time.Time, *time.Time, error) {
+	if i.DueAt != nil || i.ClearDueAt {
+		dueAt = i.DueAt
+	}
+
+	if i.RemindAt != nil || i.ClearRemindAt {
+		remindAt = i.RemindAt
+	}
+
+	if dueAt != nil && remindAt != nil && remindAt.After(*dueAt) {
+		return nil, nil, errRemindAfterDue
+	}
+
+	return dueAt, remindAt, nil
+}
+
+// UpdateSeriesInput changes every undone item of a recurring series. Setting Recurrence
+// on an item that does not repeat yet starts a series with it.
+type UpdateSeriesInput struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Priority    *Priority `json:"priority"`
+	Recurrence  *string   `json:"recurrence"`
+}
+
+func (i UpdateSeriesInput) Validate() error {
+	if i.Title == nil && i.Description == nil && i.Priority == nil && i.Recurrence == nil {
+		return NewValidationError("update structure has no values")
+	}
+
+	return nil
+}
+
+type ReorderItemsInput struct {
+	ItemIds []int `json:"item_ids" binding:"required"`
+}
+
+type MoveItemInput struct {
+	ListId int `json:"list_id" binding:"required"`
+}
+
+type ItemPositionInput struct {
+	BeforeId *int `json:"before_id"`
+	AfterId  *int `json:"after_id"`
+}
+
+func (i ItemPositionInput) Validate() error {
+	if (i.BeforeId == nil) == (i.AfterId == nil) {
+		return NewValidationError("exactly one of before_id and after_id is required")
 	}
 
 	return nil
 }
+
+// Anchor returns the item to place next to and whether to place before it.
+func (i ItemPositionInput) Anchor() (int, bool) {
+	if i.BeforeId != nil {
+		return *i.BeforeId, true
+	}
+
+	return *i.AfterId, false
+}
+
+const MaxPageLimit = 100
+
+type Page struct {
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"`
+	Sort   string `form:"sort"`
+}
+
+func (p Page) Validate() error {
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return NewValidationError(fmt.Sprintf("limit must be between 1 and %d, or 0 for the default", MaxPageLimit))
+	}
+
+	return nil
+}
+
+type ListFilter struct {
+	Page
+	Q string `form:"q"`
+}
+
+type ItemFilter struct {
+	Page
+	Q         string     `form:"q"`
+	Done      *bool      `form:"done"`
+	DueBefore *time.Time `form:"due_before"`
+	DueAfter  *time.Time `form:"due_after"`
+	Overdue   bool       `form:"overdue"`
+	Label     *int       `form:"label"`
+
+	// MinPriority is set by the smart views, it is not a query parameter.
+	MinPriority *Priority `form:"-"`
+}
+
+// View names a server-side item query across all lists of the user.
+type View string
+
+const (
+	ViewToday        View = "today"
+	ViewUpcoming     View = "upcoming"
+	ViewOverdue      View = "overdue"
+	ViewHighPriority View = "high-priority"
+	ViewCompleted    View = "completed"
+)
+
+// UpcomingDays is how far ahead the upcoming view looks, starting tomorrow.
+const UpcomingDays = 7
+
+type ViewFilter struct {
+	Page
+	TimeZone string `form:"tz"`
+}
+
+func (f ViewFilter) Location() (*time.Location, error) {
+	if f.TimeZone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(f.TimeZone)
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("unknown time zone %q", f.TimeZone))
+	}
+
+	return loc, nil
+}
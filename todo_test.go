@@ -0,0 +1,81 @@
+package todolist_app
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUpdateItemInputNulls(t *testing.T) {
+	due := time.Date(2030, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		body                      string
+		dueAt, remindAt           *time.Time
+		clearDueAt, clearRemindAt bool
+	}{
+		{`{"title":"x"}`, nil, nil, false, false},
+		{`{"due_at":"2030-01-10T09:00:00Z"}`, &due, nil, false, false},
+		{`{"due_at":null}`, nil, nil, true, false},
+		{`{"due_at":"2030-01-10T09:00:00Z","remind_at":null}`, &due, nil, false, true},
+		{`{"due_at":null,"remind_at":null}`, nil, nil, true, true},
+	}
+
+	for _, tc := range tests {
+		var input UpdateItemInput
+		if err := json.Unmarshal([]byte(tc.body), &input); err != nil {
+			t.Fatalf("decoding %s: %v", tc.body, err)
+		}
+
+		sameTime := func(a, b *time.Time) bool { return a == nil && b == nil || a != nil && b != nil && a.Equal(*b) }
+		if !sameTime(input.DueAt, tc.dueAt) || !sameTime(input.RemindAt, tc.remindAt) ||
+			input.ClearDueAt != tc.clearDueAt || input.ClearRemindAt != tc.clearRemindAt {
+			t.Fatalf("decoding %s returned %+v", tc.body, input)
+		}
+
+		if err := input.Validate(); err != nil {
+			t.Fatalf("%s does not validate: %v", tc.body, err)
+		}
+	}
+
+	var input UpdateItemInput
+	if err := json.Unmarshal([]byte(`{"title":"x","priority":"high","due_at":"tomorrow"}`), &input); err == nil {
+		t.Fatal("decoding an invalid due_at succeeded")
+	}
+
+	if err := json.Unmarshal([]byte(`{"title":"x","priority":"high"}`), &input); err != nil || *input.Title != "x" ||
+		*input.Priority != PriorityHigh {
+		t.Fatalf("decoding the other fields returned %+v, %v", input, err)
+	}
+}
+
+func TestUpdateItemInputSchedule(t *testing.T) {
+	due := time.Date(2030, 1, 10, 9, 0, 0, 0, time.UTC)
+	remind := due.Add(-time.Hour)
+	late, early := due.Add(time.Hour), remind.Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		input UpdateItemInput
+		ok    bool
+	}{
+		{"unchanged", UpdateItemInput{}, true},
+		{"reminder after the stored due time", UpdateItemInput{RemindAt: &late}, false},
+		{"due time before the stored reminder", UpdateItemInput{DueAt: &early}, false},
+		{"both moved", UpdateItemInput{DueAt: &late, RemindAt: &due}, true},
+		{"due time cleared", UpdateItemInput{ClearDueAt: true, RemindAt: &late}, true},
+		{"reminder cleared", UpdateItemInput{ClearRemindAt: true, DueAt: &early}, true},
+	}
+
+	for _, tc := range tests {
+		_, _, err := tc.input.Schedule(&due, &remind)
+		if tc.ok && err != nil || !tc.ok && ErrorCodeOf(err) != CodeValidation {
+			t.Fatalf("%s: Schedule returned %v", tc.name, err)
+		}
+	}
+
+	dueAt, remindAt, err := UpdateItemInput{ClearDueAt: true}.Schedule(&due, &remind)
+	if err != nil || dueAt != nil || remindAt != &remind {
+		t.Fatalf("clearing the due time returned %v, %v, %v", dueAt, remindAt, err)
+	}
+}
@@ -0,0 +1,18 @@
+package todolist_app
+
+import "time"
+
+const (
+	TrashKindList = "list"
+	TrashKindItem = "item"
+)
+
+// TrashEntry is a deleted list or item that can still be restored. Items of a deleted list
+// are not listed on their own, they come back with the list.
+type TrashEntry struct {
+	Kind      string    `json:"kind" db:"kind"`
+	Id        int       `json:"id" db:"id"`
+	ListId    int       `json:"list_id" db:"list_id"`
+	Title     string    `json:"title" db:"title"`
+	DeletedAt time.Time `json:"deleted_at" db:"deleted_at"`
+}
@@ -1,8 +1,26 @@
 package todolist_app
 
+import "time"
+
 type User struct {
 	Id       int    `json:"-" db:"id"`
-	Name     string `json:"name" binding:"required"`
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Name     string `json:"name" db:"name" binding:"required"`
+	Username string `json:"username" db:"username" binding:"required"`
+	Password string `json:"password" db:"password_hash" binding:"required"`
+}
+
+type RefreshToken struct {
+	Id        int        `db:"id"`
+	UserId    int        `db:"user_id"`
+	SessionId string     `db:"session_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }